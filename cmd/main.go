@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -10,24 +11,193 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"RSSHub/internal/adapters/api"
+	"RSSHub/internal/adapters/backup"
+	"RSSHub/internal/adapters/control"
 	"RSSHub/internal/adapters/db"
+	"RSSHub/internal/adapters/memory"
+	"RSSHub/internal/adapters/opml"
+	"RSSHub/internal/adapters/rss"
+	"RSSHub/internal/adapters/sqlite"
 	"RSSHub/internal/domain"
 	"RSSHub/internal/domain/utils"
+	"RSSHub/migrations"
+	"RSSHub/pkg/config"
 	"RSSHub/pkg/lock"
 	"RSSHub/pkg/logger"
 )
 
+// commandTimeout bounds the short-lived commands, everything they do must finish by then
+const commandTimeout = 2 * time.Minute
+
+// feedTimeout bounds fetching a single feed in place by fetch-now
+const feedTimeout = 2 * time.Minute
+
+// controlSocket returns the socket of the fetch daemon: RSSHUB_CONTROL_SOCKET when it is set,
+// otherwise one derived from the storage, so that daemons of different databases do not collide
+func controlSocket() string {
+	if socket := config.GetEnvControlSocket(); socket != "" {
+		return socket
+	}
+	storage := config.GetEnvStorage()
+	if storage == "" || storage == "postgres" {
+		if dbConfig, err := config.GetDBConfig(); err == nil {
+			storage = dbConfig.DSN
+		}
+	} else if path, ok := strings.CutPrefix(storage, "sqlite://"); ok {
+		if abs, err := filepath.Abs(path); err == nil {
+			storage = "sqlite://" + abs
+		}
+	}
+	return control.SocketPath(storage)
+}
+
+// openStorage connects to the backend chosen by RSSHUB_STORAGE: Postgres by default,
+// a SQLite file with sqlite:///path/to/rsshub.db, or memory for dry runs that keep nothing.
+// The SQLite and memory schemas are migrated automatically unless autoMigrate is false
+func openStorage(ctx context.Context, autoMigrate bool) (domain.Storage, []domain.Migration, error) {
+	storage := config.GetEnvStorage()
+	if storage == "memory" {
+		allMigrations, err := migrations.LoadSQLite()
+		if err != nil {
+			return nil, nil, err
+		}
+		repo := memory.NewMemoryRepository()
+		if autoMigrate {
+			if _, err := repo.MigrateUp(ctx, allMigrations); err != nil {
+				return nil, nil, err
+			}
+		}
+		return repo, allMigrations, nil
+	}
+	if storage == "" || storage == "postgres" {
+		dbConfig, err := config.GetDBConfig()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid db configuration: %w", err)
+		}
+		allMigrations, err := migrations.Load()
+		if err != nil {
+			return nil, nil, err
+		}
+		repo, err := db.NewPostgresRepository(ctx, dbConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, allMigrations, nil
+	}
+
+	path, ok := strings.CutPrefix(storage, "sqlite://")
+	if !ok || path == "" {
+		return nil, nil, fmt.Errorf("unsupported RSSHUB_STORAGE %q, use postgres, sqlite:///path/to/file.db or memory", storage)
+	}
+	allMigrations, err := migrations.LoadSQLite()
+	if err != nil {
+		return nil, nil, err
+	}
+	repo, err := sqlite.NewSQLiteRepository(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A local file belongs to this user alone, so its schema is kept current automatically
+	if autoMigrate {
+		if _, err := repo.MigrateUp(ctx, allMigrations); err != nil {
+			repo.Close()
+			return nil, nil, err
+		}
+	}
+	return repo, allMigrations, nil
+}
+
+// articleFlags describes the read and starred state of an article for listings
+func articleFlags(a domain.Article) string {
+	var flags []string
+	if !a.Read {
+		flags = append(flags, "unread")
+	}
+	if a.Starred {
+		flags = append(flags, "starred")
+	}
+	if len(flags) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(flags, ", ") + ")"
+}
+
+// errWebPage is returned by checkFeedURL for HTML pages, whose feeds 'add' can look for
+var errWebPage = errors.New("The url leads to a web page, not to a feed")
+
+// checkFeedURL makes sure the URL serves an RSS feed this app can parse and returns it
+func checkFeedURL(feedURL string) (*domain.RSSFeed, error) {
+	resp, err := http.Get(feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("Could not access the site through url: %s", feedURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Got an unexpected code from url: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the RSS body :(")
+	}
+
+	if rss.IsWebPage(resp.Header.Get("Content-Type"), data) {
+		return nil, errWebPage
+	}
+
+	var testFeed domain.RSSFeed
+	if err := xml.Unmarshal(data, &testFeed); err != nil {
+		return nil, fmt.Errorf("Could not parse the RSS body :(")
+	}
+
+	if testFeed.Channel.Title == "" {
+		return nil, fmt.Errorf("Our struct does not work with this site!")
+	}
+	return &testFeed, nil
+}
+
+// printFeedMeta prints the metadata the feed has about itself, skipping the empty fields
+func printFeedMeta(m domain.FeedMeta) {
+	for _, field := range []struct{ label, value string }{
+		{"Title", m.Title},
+		{"Site", m.SiteURL},
+		{"Description", m.Description},
+		{"Language", m.Language},
+		{"Image", m.ImageURL},
+		{"Icon", m.IconURL},
+	} {
+		if field.value != "" {
+			fmt.Printf("   %s: %s\n", field.label, field.value)
+		}
+	}
+}
+
+// feedFilter builds the filter of the --folder and --tag flags
+func feedFilter(folder, tag string) domain.FeedFilter {
+	return domain.FeedFilter{Folder: strings.TrimSpace(folder), Tag: strings.ToLower(strings.TrimSpace(tag))}
+}
+
 func main() {
 	logger.Init()
 	var agg *api.Aggregator
 
+	// Ctrl+C cancels whatever the command is doing
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Establishing DB connection
-	repo, err := db.NewPostgresRepository()
+	// The migrate command itself must see the schema as it is
+	autoMigrate := len(os.Args) < 2 || os.Args[1] != "migrate"
+	repo, allMigrations, err := openStorage(ctx, autoMigrate)
 	if err != nil {
 		log.Fatalf("DB connect failed: %v", err)
 	}
@@ -44,8 +214,29 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Long running commands manage their own deadlines
+	if os.Args[1] != "fetch" && os.Args[1] != "fetch-now" &&
+		os.Args[1] != "import" && os.Args[1] != "backup" && os.Args[1] != "restore" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+	}
+
 	switch os.Args[1] {
 	case "fetch":
+		fetchCmd := flag.NewFlagSet("fetch", flag.ExitOnError)
+		once := fetchCmd.Bool("once", false, "Fetch all due feeds once and exit")
+		fetchCmd.Parse(os.Args[2:])
+
+		// Refusing to work with a schema this binary does not know
+		schema, err := repo.MigrationStatus(ctx, allMigrations)
+		if err != nil {
+			log.Fatalf("failed to check database schema: %v", err)
+		}
+		if !schema.UpToDate() {
+			log.Fatalf("database schema is at version %d (dirty: %v) but %d is required, run 'rsshub migrate up'", schema.Current, schema.Dirty, schema.Latest)
+		}
+
 		// lock.Release()
 		// Locking the fetch command, so that that there would not be 2 'fetch' funning apps
 		if err := lock.Acquire(); err != nil {
@@ -53,10 +244,6 @@ func main() {
 		}
 		defer lock.Release()
 
-		// Introducing Ctrl+C signal
-		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-		defer stop()
-
 		// Introducing aggregator
 		cliInterval, err := utils.GetAndParseCliInterval()
 		if err != nil {
@@ -73,8 +260,84 @@ func main() {
 			log.Fatalf("number of workers cannot be 0")
 		}
 
+		retention, err := utils.GetRetentionPolicy()
+		if err != nil {
+			stop()
+			log.Fatalf("failed to fetch retention rules from env file: %v", err)
+		}
+
 		agg = api.NewAggregator(cliInterval, workersNum, repo)
 
+		// Staying paused if fetching was paused before the restart
+		pause, err := repo.FetchPauseState(ctx)
+		if err == nil && pause.Active(time.Now()) {
+			if *once {
+				fmt.Println("Fetching is paused, nothing to do")
+				break
+			}
+			agg.Pause(pause.Until)
+			fmt.Println("Fetching is paused, run 'rsshub resume' to continue")
+		}
+
+		if *once {
+			// Fetching the feeds which have not been updated for a whole interval, their own or the global one
+			now := time.Now()
+			stale, err := repo.ListDueFeeds(ctx, now)
+			if err != nil {
+				stop()
+				lock.Release()
+				log.Fatalf("failed to list due feeds: %v", err)
+			}
+			var feeds []domain.Feed
+			for _, feed := range stale {
+				if feed.Due(now, cliInterval) {
+					feeds = append(feeds, feed)
+				}
+			}
+
+			results, err := agg.RunOnce(ctx, feeds)
+			if err != nil {
+				fmt.Printf("Fetch interrupted: %v\n", err)
+			}
+
+			var total domain.IngestStats
+			failed := 0
+			fmt.Println("\n# Fetch summary")
+			for _, res := range results {
+				if res.Err != nil {
+					fmt.Printf("%s: failed: %v\n", res.Feed.Name, res.Err)
+					failed++
+					continue
+				}
+				fmt.Printf("%s: %d new, %d updated, %d skipped\n", res.Feed.Name, res.Stats.New, res.Stats.Updated, res.Stats.Skipped)
+				total.New += res.Stats.New
+				total.Updated += res.Stats.Updated
+				total.Skipped += res.Stats.Skipped
+			}
+			fmt.Printf("\n%d of %d due feed(s) fetched, %d failed: %d new, %d updated, %d skipped articles\n",
+				len(results)-failed, len(feeds), failed, total.New, total.Updated, total.Skipped)
+
+			// Cron runs have no janitor, so the retention rules are applied here
+			pruned, err := api.PruneFeeds(ctx, repo, retention, false)
+			if err != nil {
+				fmt.Printf("Pruning failed: %v\n", err)
+			}
+			prunedCount := 0
+			for _, res := range pruned {
+				prunedCount += len(res.Articles)
+			}
+			if prunedCount > 0 {
+				fmt.Printf("%d old article(s) pruned\n", prunedCount)
+			}
+
+			if failed > 0 || len(results) < len(feeds) {
+				stop()
+				lock.Release()
+				os.Exit(1)
+			}
+			break
+		}
+
 		// Starting feed fetch
 		if err := agg.Start(ctx); err != nil {
 			stop()
@@ -82,6 +345,17 @@ func main() {
 		}
 		fmt.Printf("The background process for fetching feeds has started (interval = %v, workers = %d)\n", cliInterval, workersNum)
 
+		// Pruning articles according to the retention rules every hour
+		janitor := api.NewJanitor(repo, retention)
+		janitor.Start(ctx)
+
+		// How long in-flight feeds may take to finish on shutdown
+		drainTimeout, err := utils.GetAndParseShutdownTimeout()
+		if err != nil {
+			stop()
+			log.Fatalf("failed to fetch shutdown timeout value from env file: %v", err)
+		}
+
 		// Introducing Sharegator
 		dbInterval, err := utils.GetAndParseDBInterval()
 		if err != nil {
@@ -93,72 +367,404 @@ func main() {
 		// Update the current feed fetch interval
 		share.UpdateShare(dbInterval, workersNum, ctx)
 
+		// Opening the control socket for the other CLI commands
+		server := control.NewServer(agg, share, repo, controlSocket())
+		if err := server.Start(ctx); err != nil {
+			stop()
+			log.Fatalf("failed to start control socket: %v", err)
+		}
+
 		// Waiting for Ctrl+C
 		<-ctx.Done()
-		agg.Stop()
+		server.Stop()
+		logger.Debug("Control socket closed")
+		fmt.Printf("Shutting down: waiting up to %v for in-flight feeds\n", drainTimeout)
+		interrupted := agg.Stop(drainTimeout)
+		for _, feed := range interrupted {
+			fmt.Printf("Interrupted: %s (queued again for the next run)\n", feed.Name)
+		}
 		logger.Debug("Aggregator stopped cleanly")
 		share.Stop()
 		logger.Debug("Sharegator stopped cleanly")
+		janitor.Stop()
+		logger.Debug("Janitor stopped cleanly")
 		fmt.Println("Graceful shutdown: aggregator stopped")
 
+	case "migrate":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: rsshub migrate up|down|status [--steps N]")
+			os.Exit(1)
+		}
+		migrateCmd := flag.NewFlagSet("migrate", flag.ExitOnError)
+		steps := migrateCmd.Int("steps", 1, "Number of migrations to roll back with 'down'")
+		migrateCmd.Parse(os.Args[3:])
+
+		switch os.Args[2] {
+		case "up":
+			applied, err := repo.MigrateUp(ctx, allMigrations)
+			for _, m := range applied {
+				fmt.Printf("Applied %d_%s\n", m.Version, m.Name)
+			}
+			if err != nil {
+				log.Fatalf("migrate up: %v", err)
+			}
+			if len(applied) == 0 {
+				fmt.Println("The database schema is up to date")
+			}
+
+		case "down":
+			if *steps <= 0 {
+				fmt.Println("The number of steps should be more than 0")
+				os.Exit(1)
+			}
+			reverted, err := repo.MigrateDown(ctx, allMigrations, *steps)
+			for _, m := range reverted {
+				fmt.Printf("Rolled back %d_%s\n", m.Version, m.Name)
+			}
+			if err != nil {
+				log.Fatalf("migrate down: %v", err)
+			}
+			if len(reverted) == 0 {
+				fmt.Println("There is nothing to roll back")
+			}
+
+		case "status":
+			status, err := repo.MigrationStatus(ctx, allMigrations)
+			if err != nil {
+				log.Fatalf("migrate status: %v", err)
+			}
+			fmt.Printf("Schema version: %d (latest %d)", status.Current, status.Latest)
+			if status.Dirty {
+				fmt.Print(", dirty")
+			}
+			fmt.Println()
+			for _, m := range status.Applied {
+				fmt.Printf("  [applied] %d_%s\n", m.Version, m.Name)
+			}
+			for _, m := range status.Pending {
+				fmt.Printf("  [pending] %d_%s\n", m.Version, m.Name)
+			}
+
+		default:
+			fmt.Println("Usage: rsshub migrate up|down|status [--steps N]")
+			os.Exit(1)
+		}
+
 	case "add":
 		addCmd := flag.NewFlagSet("add", flag.ExitOnError)
 		feedName := addCmd.String("name", "", "Feed name")
 		feedURL := addCmd.String("url", "", "Feed URL")
+		folder := addCmd.String("folder", "", "Folder to put the feed in")
+		tagList := addCmd.String("tag", "", "Comma-separated tags")
+		pick := addCmd.Int("pick", 0, "Which of the feeds found on a web page to add")
 		addCmd.Parse(os.Args[2:])
 
-		if *feedName == "" || *feedURL == "" {
-			fmt.Println("Usage: rsshub add --name <feed-name> --url <feed-url>")
+		if *feedName == "" || *feedURL == "" || *pick < 0 {
+			fmt.Println("Usage: rsshub add --name <feed-name> --url <feed-url> [--folder <folder>] [--tag a,b] [--pick N]")
 			os.Exit(1)
 		}
 
-		resp, err := http.Get(*feedURL)
+		tags, err := utils.ParseTags(*tagList)
 		if err != nil {
-			fmt.Printf("Could not access the site through url: %s\n", *feedURL)
+			fmt.Println(err)
 			os.Exit(1)
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			fmt.Printf("Got an unexpected code from url: %d\n", resp.StatusCode)
+		parsed, err := checkFeedURL(*feedURL)
+		if errors.Is(err, errWebPage) {
+			// Looking for the feeds of the page instead
+			candidates, discoverErr := rss.DiscoverFeeds(ctx, *feedURL)
+			if discoverErr != nil {
+				fmt.Printf("Could not look for feeds on the web page: %v\n", discoverErr)
+				os.Exit(1)
+			}
+
+			chosen, readable, chooseErr := rss.ChooseFeed(candidates, *pick)
+			switch {
+			case errors.Is(chooseErr, rss.ErrPickFeed):
+				fmt.Printf("Found %d feeds on this web page:\n", len(readable))
+				for i, c := range readable {
+					fmt.Printf("%d. %s", i+1, c.URL)
+					if c.Title != "" {
+						fmt.Printf(" (%s)", c.Title)
+					}
+					fmt.Println()
+				}
+				fmt.Println("Run the command again with --pick N to add one of them")
+				os.Exit(1)
+			case errors.Is(chooseErr, rss.ErrUnsupportedFeeds):
+				fmt.Println(chooseErr)
+				for _, c := range candidates {
+					fmt.Printf("   %s (%s)\n", c.URL, c.Kind)
+				}
+				os.Exit(1)
+			case chooseErr != nil:
+				fmt.Println(chooseErr)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Found the feed %s\n", chosen.URL)
+			*feedURL = chosen.URL
+			parsed, err = checkFeedURL(*feedURL)
+		}
+		if err != nil {
+			fmt.Println(err)
 			os.Exit(1)
 		}
 
-		data, err := io.ReadAll(resp.Body)
+		// The feed counts as never fetched until UpdatedAt moves past CreatedAt
+		now := time.Now()
+		feed := domain.Feed{
+			Name:      *feedName,
+			URL:       *feedURL,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Folder:    strings.TrimSpace(*folder),
+			Tags:      tags,
+			Meta:      api.FeedMeta(ctx, parsed, *feedURL, domain.FeedMeta{}),
+		}
+
+		logger.Debug("Adding feed to the DB...", "feed", feed)
+		err = repo.AddFeed(ctx, feed)
 		if err != nil {
-			fmt.Printf("Could not read the RSS body :(\n")
+			log.Fatalf("failed to insert feed: %v", err)
+		}
+
+		fmt.Printf("Feed '%s' added successfully!\n", *feedName)
+
+	case "import":
+		importCmd := flag.NewFlagSet("import", flag.ExitOnError)
+		opmlPath := importCmd.String("opml", "", "OPML file exported by another feed reader")
+		validate := importCmd.Bool("validate", false, "Fetch every feed first and only add the readable ones")
+		importCmd.Parse(os.Args[2:])
+
+		if *opmlPath == "" {
+			fmt.Println("Usage: rsshub import --opml <file.opml> [--validate]")
 			os.Exit(1)
 		}
 
-		var testFeed domain.RSSFeed
-		if err := xml.Unmarshal(data, &testFeed); err != nil {
-			fmt.Printf("Could not parse the RSS body :(\n")
+		file, err := os.Open(*opmlPath)
+		if err != nil {
+			log.Fatalf("failed to open OPML file: %v", err)
+		}
+		feeds, err := opml.Parse(file)
+		file.Close()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if len(feeds) == 0 {
+			fmt.Println("The OPML file has no feeds")
+			break
+		}
+
+		if *validate {
+			fmt.Printf("Validating %d feed(s)...\n", len(feeds))
+		}
+		results, err := api.ImportFeeds(ctx, repo, feeds, *validate)
+		if err != nil && results == nil {
+			log.Fatalf("failed to import feeds: %v", err)
+		}
+
+		counts := make(map[api.ImportStatus]int)
+		for _, res := range results {
+			counts[res.Status]++
+			if res.Status == "" {
+				continue
+			}
+			fmt.Printf("%-8s %s (%s)", res.Status, res.Feed.Name, res.Feed.URL)
+			if res.Feed.Folder != "" {
+				fmt.Printf(" in %s", res.Feed.Folder)
+			}
+			if res.Reason != "" {
+				fmt.Printf(": %s", res.Reason)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("\n%d added, %d skipped, %d failed\n", counts[api.ImportAdded], counts[api.ImportSkipped], counts[api.ImportFailed])
+		if err != nil {
+			fmt.Printf("Import interrupted: %v\n", err)
+		}
+		if err != nil || counts[api.ImportFailed] > 0 {
 			os.Exit(1)
 		}
 
-		if testFeed.Channel.Title == "" {
-			fmt.Printf("Our struct does not work with this site!\n")
+	case "export":
+		exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+		asOPML := exportCmd.Bool("opml", false, "Write the feeds as OPML 2.0")
+		output := exportCmd.String("o", "", "Output file (default: standard output)")
+		exportCmd.Parse(os.Args[2:])
+
+		// OPML is the only format for now, the flag keeps room for others
+		if !*asOPML {
+			fmt.Println("Usage: rsshub export --opml [-o <file>]")
 			os.Exit(1)
 		}
 
-		feed := domain.Feed{
-			Name:      *feedName,
-			URL:       *feedURL,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+		feeds, err := repo.ListFeeds(ctx, domain.FeedFilter{}, 0)
+		if err != nil {
+			log.Fatalf("failed to list feeds: %v", err)
 		}
 
-		logger.Debug("Adding feed to the DB...", "feed", feed)
-		err = repo.AddFeed(feed)
+		if *output == "" {
+			if err := opml.Write(os.Stdout, feeds, time.Now()); err != nil {
+				log.Fatalf("failed to write OPML: %v", err)
+			}
+			break
+		}
+
+		file, err := os.Create(*output)
 		if err != nil {
-			log.Fatalf("failed to insert feed: %v", err)
+			log.Fatalf("failed to create output file: %v", err)
 		}
+		if err := opml.Write(file, feeds, time.Now()); err != nil {
+			file.Close()
+			log.Fatalf("failed to write OPML: %v", err)
+		}
+		if err := file.Close(); err != nil {
+			log.Fatalf("failed to write OPML: %v", err)
+		}
+		fmt.Printf("%d feed(s) exported to %s\n", len(feeds), *output)
 
-		fmt.Printf("Feed '%s' added successfully!\n", *feedName)
+	case "backup":
+		backupCmd := flag.NewFlagSet("backup", flag.ExitOnError)
+		output := backupCmd.String("o", "", "Archive to write, e.g. rsshub.tar.gz")
+		backupCmd.Parse(os.Args[2:])
+
+		if *output == "" {
+			fmt.Println("Usage: rsshub backup -o <file.tar.gz>")
+			os.Exit(1)
+		}
+
+		// The archive only takes its name once complete, so a failed backup never replaces a good one
+		tmpPath := *output + ".tmp"
+		file, err := os.Create(tmpPath)
+		if err != nil {
+			log.Fatalf("failed to create archive: %v", err)
+		}
+		manifest, err := backup.Write(ctx, repo, file, time.Now())
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmpPath, *output)
+		}
+		if err != nil {
+			os.Remove(tmpPath)
+			log.Fatalf("failed to back up: %v", err)
+		}
+		fmt.Printf("%d feed(s) and %d article(s) backed up to %s\n", manifest.Feeds, manifest.Articles, *output)
+
+	case "restore":
+		restoreCmd := flag.NewFlagSet("restore", flag.ExitOnError)
+		input := restoreCmd.String("i", "", "Archive written by rsshub backup")
+		onConflict := restoreCmd.String("on-conflict", string(backup.Merge), "What to do with feeds already present: skip, merge or replace")
+		restoreCmd.Parse(os.Args[2:])
+
+		strategy, err := backup.ParseStrategy(*onConflict)
+		if *input == "" || err != nil {
+			if err != nil {
+				fmt.Println(err)
+			}
+			fmt.Println("Usage: rsshub restore -i <file.tar.gz> [--on-conflict skip|merge|replace]")
+			os.Exit(1)
+		}
+
+		file, err := os.Open(*input)
+		if err != nil {
+			log.Fatalf("failed to open archive: %v", err)
+		}
+		result, err := backup.Restore(ctx, repo, file, strategy)
+		file.Close()
+		if err != nil && result.Manifest.Format == "" {
+			log.Fatalf("failed to restore: %v", err)
+		}
+
+		fmt.Printf("Feeds: %d added, %d merged, %d replaced, %d skipped\n",
+			result.FeedsAdded, result.FeedsMerged, result.FeedsReplaced, result.FeedsSkipped)
+		fmt.Printf("Articles: %d new, %d updated, %d skipped\n",
+			result.Articles.New, result.Articles.Updated, result.Articles.Skipped)
+		if result.Pruned > 0 {
+			fmt.Printf("Pruned articles remembered: %d\n", result.Pruned)
+		}
+		if result.Settings {
+			fmt.Println("Fetch settings restored")
+		}
+		if err != nil {
+			fmt.Printf("Restore interrupted: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "update":
+		updateCmd := flag.NewFlagSet("update", flag.ExitOnError)
+		name := updateCmd.String("name", "", "Name of the feed to update")
+		newName := updateCmd.String("new-name", "", "New feed name")
+		feedURL := updateCmd.String("url", "", "New feed URL")
+		interval := updateCmd.String("interval", "", "Fetch interval of this feed, e.g. 2h (\"default\" for the global one)")
+		folder := updateCmd.String("folder", "", "Folder to move the feed to (\"\" to take it out of its folder)")
+		updateCmd.Parse(os.Args[2:])
+
+		// Only the flags given on the command line are changed
+		set := make(map[string]bool)
+		updateCmd.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+		if *name == "" || len(set) == 1 {
+			fmt.Println("Usage: rsshub update --name <feed-name> [--new-name <name>] [--url <url>] [--interval 2h|default] [--folder <folder>]")
+			os.Exit(1)
+		}
+
+		feed, err := repo.ListFeedByName(ctx, *name)
+		if err != nil {
+			fmt.Printf("This feed have not yet been uploaded to the program or was deleted!\n")
+			os.Exit(1)
+		}
+
+		if set["new-name"] {
+			if strings.TrimSpace(*newName) == "" {
+				fmt.Println("The new name cannot be empty")
+				os.Exit(1)
+			}
+			feed.Name = strings.TrimSpace(*newName)
+		}
+		urlChanged := set["url"] && *feedURL != feed.URL
+		if urlChanged {
+			parsed, err := checkFeedURL(*feedURL)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			feed.URL = *feedURL
+			// The title, site and favicon of the old feed say nothing about the new one
+			feed.Meta = api.FeedMeta(ctx, parsed, *feedURL, domain.FeedMeta{})
+		}
+		if set["interval"] {
+			if *interval == "default" {
+				feed.Interval = 0
+			} else if feed.Interval, err = utils.ParseIntervalToDuration(*interval); err != nil {
+				log.Fatalf("invalid interval: %v\n", err)
+			}
+		}
+		if set["folder"] {
+			feed.Folder = strings.TrimSpace(*folder)
+		}
+
+		if err := repo.UpdateFeed(ctx, *name, feed); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if urlChanged {
+			if err := repo.UpdateFeedMeta(ctx, feed.ID, feed.Meta); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		fmt.Printf("Feed '%s' updated successfully\n", feed.Name)
 
 	case "list":
 		listCmd := flag.NewFlagSet("list", flag.ExitOnError)
 		feedNum := listCmd.Int("num", 0, "Number of feeds to display (default: all)")
+		folder := listCmd.String("folder", "", "Only list the feeds of this folder")
+		tag := listCmd.String("tag", "", "Only list the feeds with this tag")
+		verbose := listCmd.Bool("verbose", false, "Also show the title, site, description and icon of each feed")
 		listCmd.Parse(os.Args[2:])
 
 		if *feedNum < 0 {
@@ -166,18 +772,85 @@ func main() {
 			os.Exit(1)
 		}
 
-		feeds, err := repo.ListFeeds(*feedNum)
+		feeds, err := repo.ListFeeds(ctx, feedFilter(*folder, *tag), *feedNum)
 		if err != nil {
 			log.Fatalf("failed to list feeds: %v", err)
 		}
+		unread, err := repo.CountUnreadArticles(ctx)
+		if err != nil {
+			log.Fatalf("failed to count unread articles: %v", err)
+		}
 
 		fmt.Println("\n# Available RSS Feeds")
 		for i, f := range feeds {
-			fmt.Printf("%d. Name: %s\n   URL: %s\n   Added: %s\n\n",
-				i+1, f.Name, f.URL, f.CreatedAt.Format("2006-01-02 15:04"),
-			)
+			fmt.Printf("%d. Name: %s\n   URL: %s\n", i+1, f.Name, f.URL)
+			if f.Folder != "" {
+				fmt.Printf("   Folder: %s\n", f.Folder)
+			}
+			if len(f.Tags) > 0 {
+				fmt.Printf("   Tags: %s\n", strings.Join(f.Tags, ", "))
+			}
+			if f.Interval > 0 {
+				interval, _ := utils.ParseDurationToInterval(f.Interval)
+				fmt.Printf("   Interval: %s\n", interval)
+			}
+			if *verbose {
+				printFeedMeta(f.Meta)
+			}
+			fmt.Printf("   Added: %s\n   Unread: %d\n\n", f.CreatedAt.Format("2006-01-02 15:04"), unread[f.ID])
+		}
+
+	case "info":
+		infoCmd := flag.NewFlagSet("info", flag.ExitOnError)
+		name := infoCmd.String("name", "", "Feed name")
+		infoCmd.Parse(os.Args[2:])
+
+		if *name == "" {
+			fmt.Println("Usage: rsshub info --name <feed-name>")
+			os.Exit(1)
 		}
 
+		feed, err := repo.ListFeedByName(ctx, *name)
+		if err != nil {
+			fmt.Printf("This feed have not yet been uploaded to the program or was deleted!\n")
+			os.Exit(1)
+		}
+		unread, err := repo.CountUnreadArticles(ctx)
+		if err != nil {
+			log.Fatalf("failed to count unread articles: %v", err)
+		}
+		policies, err := repo.ListFeedRetention(ctx)
+		if err != nil {
+			log.Fatalf("failed to read retention rules: %v", err)
+		}
+
+		interval := "global"
+		if feed.Interval > 0 {
+			interval, _ = utils.ParseDurationToInterval(feed.Interval)
+		}
+		retention := "global"
+		if policy, ok := policies[feed.ID]; ok {
+			retention = policy.String()
+		}
+		lastFetched := "never"
+		if feed.UpdatedAt.After(feed.CreatedAt) {
+			lastFetched = feed.UpdatedAt.Format("2006-01-02 15:04")
+		}
+
+		fmt.Printf("\n# %s\n", feed.Name)
+		fmt.Printf("   URL: %s\n", feed.URL)
+		printFeedMeta(feed.Meta)
+		if feed.Folder != "" {
+			fmt.Printf("   Folder: %s\n", feed.Folder)
+		}
+		if len(feed.Tags) > 0 {
+			fmt.Printf("   Tags: %s\n", strings.Join(feed.Tags, ", "))
+		}
+		fmt.Printf("   Interval: %s\n   Retention: %s\n", interval, retention)
+		fmt.Printf("   Added: %s\n   Last fetched: %s\n   Unread: %d\n",
+			feed.CreatedAt.Format("2006-01-02 15:04"), lastFetched, unread[feed.ID],
+		)
+
 	case "delete":
 		deleteCmd := flag.NewFlagSet("delete", flag.ExitOnError)
 		feedName := deleteCmd.String("name", "", "Feed name to delete")
@@ -188,7 +861,7 @@ func main() {
 			os.Exit(1)
 		}
 
-		err := repo.DeleteFeed(*feedName)
+		err := repo.DeleteFeed(ctx, *feedName)
 		if err != nil {
 			fmt.Println(err.Error())
 			os.Exit(1)
@@ -200,10 +873,15 @@ func main() {
 		articlesCmd := flag.NewFlagSet("articles", flag.ExitOnError)
 		feedName := articlesCmd.String("feed-name", "", "Feed name to list articles for")
 		num := articlesCmd.Int("num", 3, "Number pkgof articles to show")
+		unreadOnly := articlesCmd.Bool("unread", false, "Only show unread articles")
+		starredOnly := articlesCmd.Bool("starred", false, "Only show starred articles")
+		folder := articlesCmd.String("folder", "", "Show the articles of every feed in this folder")
+		tag := articlesCmd.String("tag", "", "Show the articles of every feed with this tag")
 		articlesCmd.Parse(os.Args[2:])
 
-		if *feedName == "" {
-			fmt.Println("Usage: rsshub articles --feed-name <name> [--num N]")
+		feeds := feedFilter(*folder, *tag)
+		if (*feedName == "") == feeds.IsZero() {
+			fmt.Println("Usage: rsshub articles --feed-name <name> | --folder <folder> | --tag <tag> [--num N] [--unread] [--starred]")
 			os.Exit(1)
 		}
 
@@ -212,27 +890,327 @@ func main() {
 			os.Exit(1)
 		}
 
-		feed, err := repo.ListFeedByName(*feedName)
-		if err != nil {
-			fmt.Printf("This feed have not yet been uploaded to the program or was deleted!\n")
+		var selected []domain.Feed
+		if *feedName != "" {
+			feed, err := repo.ListFeedByName(ctx, *feedName)
+			if err != nil {
+				fmt.Printf("This feed have not yet been uploaded to the program or was deleted!\n")
+				os.Exit(1)
+			}
+			selected = append(selected, feed)
+		} else {
+			selected, err = repo.ListFeeds(ctx, feeds, 0)
+			if err != nil {
+				log.Fatalf("failed to list feeds: %v", err)
+			}
+			if len(selected) == 0 {
+				fmt.Println("No feeds match the folder or tag")
+				break
+			}
+		}
+
+		filter := domain.ArticleFilter{Unread: *unreadOnly, Starred: *starredOnly}
+		for _, feed := range selected {
+			articles, err := repo.ListArticlesByFeed(ctx, feed.ID, filter, *num)
+			if err != nil {
+				log.Fatalf("failed to fetch articles: %v", err)
+			}
+
+			fmt.Printf("Feed: %s\n\n", feed.Name)
+			for i, a := range articles {
+				fmt.Printf("%d. [%s] %s%s\n   %s\n   id: %s\n\n",
+					i+1,
+					a.PublishedAt.Format("2006-01-02"),
+					a.Title,
+					articleFlags(a),
+					a.Link,
+					a.ID,
+				)
+			}
+		}
+
+	case "timeline":
+		timelineCmd := flag.NewFlagSet("timeline", flag.ExitOnError)
+		feedNames := timelineCmd.String("feed", "", "Comma-separated feed names (default: all feeds)")
+		since := timelineCmd.String("since", "", "Only articles published after a date (2006-01-02) or within an age (2d)")
+		until := timelineCmd.String("until", "", "Only articles published before a date or an age")
+		after := timelineCmd.String("after", "", "Cursor printed at the end of the previous page")
+		num := timelineCmd.Int("num", 20, "Number of articles per page")
+		unreadOnly := timelineCmd.Bool("unread", false, "Only show unread articles")
+		starredOnly := timelineCmd.Bool("starred", false, "Only show starred articles")
+		folder := timelineCmd.String("folder", "", "Only the feeds of this folder")
+		tag := timelineCmd.String("tag", "", "Only the feeds with this tag")
+		timelineCmd.Parse(os.Args[2:])
+
+		if *num <= 0 || *num > 100 {
+			fmt.Println("The number of articles should be between 1 and 100")
 			os.Exit(1)
 		}
 
-		articles, err := repo.ListArticlesByFeed(feed.ID, *num)
+		query := domain.TimelineQuery{
+			Feeds:  feedFilter(*folder, *tag),
+			Filter: domain.ArticleFilter{Unread: *unreadOnly, Starred: *starredOnly},
+			Limit:  *num,
+		}
+		for _, name := range strings.Split(*feedNames, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				query.FeedNames = append(query.FeedNames, name)
+			}
+		}
+		now := time.Now()
+		if *since != "" {
+			if query.Since, err = utils.ParseSince(*since, now); err != nil {
+				log.Fatalf("invalid --since: %v", err)
+			}
+		}
+		if *until != "" {
+			if query.Until, err = utils.ParseSince(*until, now); err != nil {
+				log.Fatalf("invalid --until: %v", err)
+			}
+		}
+		if *after != "" {
+			cursor, err := domain.ParseTimelineCursor(*after)
+			if err != nil {
+				log.Fatalf("invalid --after: %v", err)
+			}
+			query.After = &cursor
+		}
+
+		entries, err := repo.Timeline(ctx, query)
 		if err != nil {
-			log.Fatalf("failed to fetch articles: %v", err)
+			log.Fatalf("failed to fetch the timeline: %v", err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No articles")
+			break
 		}
 
-		fmt.Printf("Feed: %s\n\n", feed.Name)
-		for i, a := range articles {
-			fmt.Printf("%d. [%s] %s\n   %s\n\n",
-				i+1,
-				a.PublishedAt.Format("2006-01-02"),
+		for _, e := range entries {
+			a := e.Article
+			fmt.Printf("[%s] %s%s (%s)\n   %s\n   id: %s\n\n",
+				a.PublishedAt.Format("2006-01-02 15:04"),
 				a.Title,
+				articleFlags(a),
+				e.FeedName,
 				a.Link,
+				a.ID,
 			)
 		}
 
+		// A full page may have more articles behind it
+		if len(entries) == *num {
+			fmt.Printf("Next page: --after %s\n", domain.CursorOf(entries[len(entries)-1].Article))
+		}
+
+	case "tag", "untag":
+		tagCmd := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+		name := tagCmd.String("name", "", "Feed name")
+		tagList := tagCmd.String("tag", "", "Comma-separated tags")
+		tagCmd.Parse(os.Args[2:])
+
+		tags, err := utils.ParseTags(*tagList)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if *name == "" || len(tags) == 0 {
+			fmt.Printf("Usage: rsshub %s --name <feed-name> --tag a,b\n", os.Args[1])
+			os.Exit(1)
+		}
+
+		if os.Args[1] == "tag" {
+			err = repo.TagFeed(ctx, *name, tags)
+		} else {
+			err = repo.UntagFeed(ctx, *name, tags)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		feed, err := repo.ListFeedByName(ctx, *name)
+		if err != nil {
+			log.Fatalf("failed to read feed: %v", err)
+		}
+		if len(feed.Tags) == 0 {
+			fmt.Printf("Feed '%s' has no tags\n", feed.Name)
+		} else {
+			fmt.Printf("Tags of feed '%s': %s\n", feed.Name, strings.Join(feed.Tags, ", "))
+		}
+
+	case "read", "star":
+		// Both commands flip one flag of an article, --undo flips it back
+		flagCmd := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+		id := flagCmd.String("id", "", "Article id, as shown by 'rsshub articles'")
+		undo := flagCmd.Bool("undo", false, "Mark the article as unread / remove the star")
+		flagCmd.Parse(os.Args[2:])
+
+		if *id == "" {
+			fmt.Printf("Usage: rsshub %s --id <article id> [--undo]\n", os.Args[1])
+			os.Exit(1)
+		}
+
+		var err error
+		done := os.Args[1]
+		if os.Args[1] == "read" {
+			err = repo.SetArticleRead(ctx, *id, !*undo)
+			if *undo {
+				done = "unread"
+			}
+		} else {
+			err = repo.SetArticleStarred(ctx, *id, !*undo)
+			done = "starred"
+			if *undo {
+				done = "unstarred"
+			}
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Article %s marked as %s\n", *id, done)
+
+	case "mark-all-read":
+		markCmd := flag.NewFlagSet("mark-all-read", flag.ExitOnError)
+		feedName := markCmd.String("feed", "", "Feed whose articles to mark as read")
+		markCmd.Parse(os.Args[2:])
+
+		if *feedName == "" {
+			fmt.Println("Usage: rsshub mark-all-read --feed <name>")
+			os.Exit(1)
+		}
+
+		feed, err := repo.ListFeedByName(ctx, *feedName)
+		if err != nil {
+			fmt.Printf("This feed have not yet been uploaded to the program or was deleted!\n")
+			os.Exit(1)
+		}
+
+		marked, err := repo.MarkFeedRead(ctx, feed.ID)
+		if err != nil {
+			log.Fatalf("failed to mark articles as read: %v", err)
+		}
+		fmt.Printf("%d article(s) of '%s' marked as read\n", marked, feed.Name)
+
+	case "search":
+		searchCmd := flag.NewFlagSet("search", flag.ExitOnError)
+		feedName := searchCmd.String("feed", "", "Only search the articles of this feed")
+		since := searchCmd.String("since", "", "Only search articles published after a date (2006-01-02) or within an age (7d)")
+		limit := searchCmd.Int("limit", 10, "Maximum number of results")
+		offset := searchCmd.Int("offset", 0, "Skip this many of the best results, to see the next page")
+
+		// The query comes first, flags may follow it
+		if len(os.Args) < 3 || strings.HasPrefix(os.Args[2], "-") {
+			fmt.Println(`Usage: rsshub search "query" [--feed name] [--since 7d] [--limit N] [--offset N]`)
+			os.Exit(1)
+		}
+		searchCmd.Parse(os.Args[3:])
+
+		if *limit <= 0 || *limit > 100 {
+			fmt.Println("The limit should be between 1 and 100")
+			os.Exit(1)
+		}
+		if *offset < 0 {
+			fmt.Println("The offset cannot be negative")
+			os.Exit(1)
+		}
+
+		query := domain.SearchQuery{Text: os.Args[2], FeedName: *feedName, Limit: *limit, Offset: *offset}
+		if *since != "" {
+			query.Since, err = utils.ParseSince(*since, time.Now())
+			if err != nil {
+				log.Fatalf("invalid --since: %v", err)
+			}
+		}
+
+		results, err := repo.SearchArticles(ctx, query)
+		if err != nil {
+			log.Fatalf("failed to search articles: %v", err)
+		}
+		if len(results) == 0 {
+			fmt.Println("Nothing found")
+			break
+		}
+
+		for i, res := range results {
+			fmt.Printf("%d. [%s] %s (%s)\n   %s\n",
+				*offset+i+1,
+				res.Article.PublishedAt.Format("2006-01-02"),
+				res.Article.Title,
+				res.FeedName,
+				res.Article.Link,
+			)
+			if res.Snippet != "" {
+				fmt.Printf("   %s\n", res.Snippet)
+			}
+			fmt.Println()
+		}
+
+	case "prune":
+		pruneCmd := flag.NewFlagSet("prune", flag.ExitOnError)
+		dryRun := pruneCmd.Bool("dry-run", false, "Only show which articles would be deleted")
+		pruneCmd.Parse(os.Args[2:])
+
+		retention, err := utils.GetRetentionPolicy()
+		if err != nil {
+			log.Fatalf("failed to fetch retention rules from env file: %v", err)
+		}
+
+		results, err := api.PruneFeeds(ctx, repo, retention, *dryRun)
+		if err != nil {
+			log.Fatalf("failed to prune articles: %v", err)
+		}
+		if len(results) == 0 {
+			fmt.Println("No retention rules are set, nothing to prune")
+			break
+		}
+
+		verb := "pruned"
+		if *dryRun {
+			verb = "would be pruned"
+		}
+		total := 0
+		for _, res := range results {
+			fmt.Printf("%s (%s): %d article(s) %s\n", res.Feed.Name, res.Policy, len(res.Articles), verb)
+			if *dryRun {
+				for _, a := range res.Articles {
+					fmt.Printf("   [%s] %s\n", a.PublishedAt.Format("2006-01-02"), a.Title)
+				}
+			}
+			total += len(res.Articles)
+		}
+		fmt.Printf("\nTotal: %d article(s) %s\n", total, verb)
+
+	case "set-retention":
+		retentionCmd := flag.NewFlagSet("set-retention", flag.ExitOnError)
+		name := retentionCmd.String("name", "", "Feed name")
+		keep := retentionCmd.Int("keep", 0, "Keep only the N newest articles (0 to use the global rule)")
+		maxAge := retentionCmd.String("max-age", "", "Delete articles older than this, e.g. 30d (empty to use the global rule)")
+		retentionCmd.Parse(os.Args[2:])
+
+		if *name == "" || *keep < 0 {
+			fmt.Println("Usage: rsshub set-retention --name <name> [--keep N] [--max-age 30d]")
+			os.Exit(1)
+		}
+
+		policy := domain.RetentionPolicy{MaxArticles: *keep}
+		if *maxAge != "" {
+			policy.MaxAge, err = utils.ParseAge(*maxAge)
+			if err != nil {
+				log.Fatalf("invalid --max-age: %v", err)
+			}
+		}
+
+		if err := repo.SetFeedRetention(ctx, *name, policy); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if policy.IsZero() {
+			fmt.Printf("Feed '%s' now follows the global retention rules\n", *name)
+		} else {
+			fmt.Printf("Retention of feed '%s' set to: %s\n", *name, policy)
+		}
+
 	case "set-interval":
 		intervalCmd := flag.NewFlagSet("set-interval", flag.ExitOnError)
 		duration := intervalCmd.String("duration", "", "New interval for fetching feeds")
@@ -248,8 +1226,20 @@ func main() {
 			log.Fatalf("invalid duration: %v\n", err)
 		}
 
+		// Let the running daemon apply the interval right away
+		resp, err := control.Send(controlSocket(), control.Request{
+			Command: control.CmdSetInterval,
+			Args:    map[string]string{"duration": *duration},
+		})
+		if err == nil {
+			fmt.Println(resp.Message)
+			break
+		} else if !errors.Is(err, control.ErrDaemonNotRunning) {
+			log.Fatalf("error updating interval: %v", err)
+		}
+
 		// Set the new interval
-		err = repo.SetInterval(*duration)
+		err = repo.SetInterval(ctx, *duration)
 		if err != nil {
 			log.Fatalf("error updating interval in db: %v", err)
 		}
@@ -267,12 +1257,189 @@ func main() {
 			os.Exit(1)
 		}
 
-		err = repo.SetWorkers(workersNum)
+		// Let the running daemon resize its worker pool right away
+		resp, err := control.Send(controlSocket(), control.Request{
+			Command: control.CmdSetWorkers,
+			Args:    map[string]string{"workers": os.Args[2]},
+		})
+		if err == nil {
+			fmt.Println(resp.Message)
+			break
+		} else if !errors.Is(err, control.ErrDaemonNotRunning) {
+			log.Fatalf("error updating workers: %v", err)
+		}
+
+		err = repo.SetWorkers(ctx, workersNum)
 		if err != nil {
 			log.Fatalf("error updating interval in db: %v", err)
 		}
 		fmt.Printf("The number of workers have changed to %v\n", workersNum)
 
+	case "status":
+		resp, err := control.Send(controlSocket(), control.Request{Command: control.CmdStatus})
+		if errors.Is(err, control.ErrDaemonNotRunning) {
+			fmt.Println("The background fetcher is not running")
+			os.Exit(1)
+		} else if err != nil {
+			log.Fatalf("failed to get status: %v", err)
+		}
+
+		st := resp.Status
+		lastTick := "never"
+		if !st.LastTick.IsZero() {
+			lastTick = fmt.Sprintf("%s (%v ago)", st.LastTick.Format("2006-01-02 15:04:05"), time.Since(st.LastTick).Round(time.Second))
+		}
+		state := "running"
+		if st.Paused && st.PausedUntil.IsZero() {
+			state = "paused"
+		} else if st.Paused {
+			state = fmt.Sprintf("paused until %s", st.PausedUntil.Format("2006-01-02 15:04:05"))
+		}
+
+		fmt.Println("\n# RSSHub fetcher status")
+		fmt.Printf("State:      %s\n", state)
+		fmt.Printf("PID:        %d\n", st.PID)
+		fmt.Printf("Uptime:     %v\n", time.Since(st.StartedAt).Round(time.Second))
+		fmt.Printf("Interval:   %s\n", st.Interval)
+		fmt.Printf("Workers:    %d (%d busy, %d idle)\n", st.Workers, st.BusyWorkers, max(st.Workers-st.BusyWorkers, 0))
+		fmt.Printf("Queue:      %d job(s)\n", st.QueueDepth)
+		fmt.Printf("Last tick:  %s\n", lastTick)
+		fmt.Printf("Last hour:  %d fetched, %d failed\n", st.FetchedLastHour, st.FailedLastHour)
+
+	case "fetch-now":
+		fetchNowCmd := flag.NewFlagSet("fetch-now", flag.ExitOnError)
+		feedName := fetchNowCmd.String("name", "", "Feed name to fetch")
+		all := fetchNowCmd.Bool("all", false, "Fetch all feeds")
+		folder := fetchNowCmd.String("folder", "", "Fetch the feeds of this folder")
+		tag := fetchNowCmd.String("tag", "", "Fetch the feeds with this tag")
+		fetchNowCmd.Parse(os.Args[2:])
+
+		// Exactly one way of picking the feeds
+		filter := feedFilter(*folder, *tag)
+		picked := 0
+		for _, set := range []bool{*feedName != "", *all, !filter.IsZero()} {
+			if set {
+				picked++
+			}
+		}
+		if picked != 1 {
+			fmt.Println("Usage: rsshub fetch-now --name <feed-name> | --all | --folder <folder> | --tag <tag>")
+			os.Exit(1)
+		}
+
+		// Asking the running daemon to fetch the feeds first
+		resp, err := control.Send(controlSocket(), control.Request{
+			Command: control.CmdFetchNow,
+			Args: map[string]string{
+				"name":   *feedName,
+				"all":    strconv.FormatBool(*all),
+				"folder": filter.Folder,
+				"tag":    filter.Tag,
+			},
+		})
+		if err == nil {
+			fmt.Println(resp.Message)
+			break
+		} else if !errors.Is(err, control.ErrDaemonNotRunning) {
+			log.Fatalf("error requesting fetch: %v", err)
+		}
+
+		// No daemon is running, so fetching right here
+		var feeds []domain.Feed
+		if *feedName == "" {
+			feeds, err = repo.ListFeeds(ctx, filter, 0)
+			if err != nil {
+				log.Fatalf("failed to list feeds: %v", err)
+			}
+			if len(feeds) == 0 {
+				fmt.Println("No feeds match the folder or tag")
+				break
+			}
+		} else {
+			feed, err := repo.ListFeedByName(ctx, *feedName)
+			if err != nil {
+				fmt.Printf("This feed have not yet been uploaded to the program or was deleted!\n")
+				os.Exit(1)
+			}
+			feeds = append(feeds, feed)
+		}
+
+		var total domain.IngestStats
+		failed := 0
+		for _, feed := range feeds {
+			feedCtx, cancel := context.WithTimeout(ctx, feedTimeout)
+			stats, err := api.FetchFeed(feedCtx, repo, feed)
+			cancel()
+			if err != nil {
+				fmt.Printf("%s: failed: %v\n", feed.Name, err)
+				failed++
+				continue
+			}
+			fmt.Printf("%s: %d new, %d updated, %d skipped\n", feed.Name, stats.New, stats.Updated, stats.Skipped)
+			total.New += stats.New
+			total.Updated += stats.Updated
+			total.Skipped += stats.Skipped
+		}
+
+		if len(feeds) > 1 {
+			fmt.Printf("\nTotal: %d new, %d updated, %d skipped, %d feed(s) failed\n", total.New, total.Updated, total.Skipped, failed)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+
+	case "pause":
+		pauseCmd := flag.NewFlagSet("pause", flag.ExitOnError)
+		duration := pauseCmd.String("for", "", "How long to pause fetching, e.g. 30m (default: until resumed)")
+		pauseCmd.Parse(os.Args[2:])
+
+		state := domain.PauseState{Paused: true}
+		if *duration != "" {
+			dur, err := utils.ParseIntervalToDuration(*duration)
+			if err != nil {
+				log.Fatalf("invalid duration: %v\n", err)
+			}
+			state.Until = time.Now().Add(dur)
+		}
+
+		resp, err := control.Send(controlSocket(), control.Request{
+			Command: control.CmdPause,
+			Args:    map[string]string{"for": *duration},
+		})
+		if err == nil {
+			fmt.Println(resp.Message)
+			break
+		} else if !errors.Is(err, control.ErrDaemonNotRunning) {
+			log.Fatalf("error pausing fetching: %v", err)
+		}
+
+		if err := repo.SetPauseState(ctx, state); err != nil {
+			log.Fatalf("error saving pause state in db: %v", err)
+		}
+		fmt.Println("Fetching will stay paused when the background fetcher starts")
+
+	case "resume":
+		resp, err := control.Send(controlSocket(), control.Request{Command: control.CmdResume})
+		if err == nil {
+			fmt.Println(resp.Message)
+			break
+		} else if !errors.Is(err, control.ErrDaemonNotRunning) {
+			log.Fatalf("error resuming fetching: %v", err)
+		}
+
+		if err := repo.SetPauseState(ctx, domain.PauseState{}); err != nil {
+			log.Fatalf("error saving pause state in db: %v", err)
+		}
+		fmt.Println("Fetching is resumed")
+
+	case "reload":
+		resp, err := control.Send(controlSocket(), control.Request{Command: control.CmdReload})
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(resp.Message)
+
 	default:
 		fmt.Printf("Unknown command: %s\n", os.Args[1])
 		os.Exit(1)
@@ -2,4 +2,7 @@ module RSSHub
 
 go 1.23.0
 
-require github.com/lib/pq v1.10.9
+require (
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.22
+)
@@ -6,216 +6,544 @@ import (
 	"sync"
 	"time"
 
-	"RSSHub/internal/adapters/rss"
 	"RSSHub/internal/domain"
-	"RSSHub/pkg/lock"
 	"RSSHub/pkg/logger"
 )
 
+// Aggregator periodically queues feeds in the durable fetch_jobs queue and runs a resizable
+// pool of workers that lease and process those jobs. Every field below mu is guarded by it.
 type Aggregator struct {
-	interval time.Duration
-	ticker   *time.Ticker
-	cancel   context.CancelFunc
-	wg       sync.WaitGroup
-	running  bool
-	mu       sync.Mutex
-
-	jobs       chan domain.Feed
-	workersNum int
-	repo       domain.Repository
+	wg   sync.WaitGroup
+	repo domain.Repository
+
+	// wake tells idle workers that new jobs were queued, so they don't wait for the next poll
+	wake chan struct{}
+
+	// results receives the outcome of every processed feed while RunOnce is waiting
+	results chan domain.FeedResult
+
+	// ctx is cancelled when in-flight fetches must be abandoned,
+	// stopDispatch only stops the ticker loop so that workers can drain
+	mu           sync.Mutex
+	ctx          context.Context
+	cancel       context.CancelFunc
+	stopDispatch context.CancelFunc
+	interval     time.Duration
+	ticker       *time.Ticker
+	running      bool
+	pause        domain.PauseState
+
+	// Each worker owns a quit channel; closing it asks that worker alone to stop
+	workersNum   int
+	workers      map[int]chan struct{}
+	nextWorkerID int
+
+	// Feeds whose fetch was cut short by the shutdown deadline
+	interrupted []domain.Feed
+
+	// Statistics shown by `rsshub status`
+	busyWorkers int
+	lastTick    time.Time
+	fetchLog    []fetchEvent
+}
 
-	// stopWorkers chan domain.StopWorker
-	stopWorkers chan int
+type fetchEvent struct {
+	at     time.Time
+	failed bool
 }
 
+const (
+	// statsWindow is how far back fetched/failed feeds are counted
+	statsWindow = time.Hour
+
+	// jobLease is how long a job stays reserved for a worker before others may pick it up again
+	jobLease = 2 * time.Minute
+
+	// jobPollInterval is how often idle workers look for jobs queued by other processes
+	jobPollInterval = 5 * time.Second
+
+	// jobRetention is how long done and failed jobs are kept in the queue table
+	jobRetention = 24 * time.Hour
+
+	// queryTimeout bounds every single DB call made by the aggregator and the share updater
+	queryTimeout = 10 * time.Second
+)
+
 var _ domain.Aggregator = (*Aggregator)(nil)
 
 func NewAggregator(defaultInterval time.Duration, workersNum int, repo domain.Repository) *Aggregator {
 	return &Aggregator{
-		interval:    defaultInterval,
-		workersNum:  workersNum,
-		jobs:        make(chan domain.Feed, 100),
-		repo:        repo,
-		stopWorkers: make(chan int),
+		interval:   defaultInterval,
+		workersNum: workersNum,
+		wake:       make(chan struct{}, 100),
+		repo:       repo,
+		workers:    make(map[int]chan struct{}),
 	}
 }
 
 func (a *Aggregator) Start(ctx context.Context) error {
 	a.mu.Lock()
-	ctx, cancel := context.WithCancel(ctx)
-	a.cancel = cancel
+	defer a.mu.Unlock()
+
+	if a.running {
+		return fmt.Errorf("aggregator is already running")
+	}
+	if a.workersNum <= 0 {
+		return fmt.Errorf("number of workers should be greater than 0")
+	}
+
+	// Ctrl+C only stops dispatching; workers are cancelled by Stop once the drain deadline passes
+	a.ctx, a.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	dispatchCtx, stopDispatch := context.WithCancel(ctx)
+	a.stopDispatch = stopDispatch
 	a.ticker = time.NewTicker(a.interval)
 	a.running = true
-	a.mu.Unlock()
 
 	// Start the worker pool with the desired number of workers
-	for i := 0; i < a.workersNum; i++ {
-		a.wg.Add(1)
-		go a.Worker(ctx, i)
-	}
+	a.spawnWorkers(a.workersNum)
 
 	// Ticker loop for loading and processing feeds at regular intervals
 	a.wg.Add(1)
-	go func() {
-		defer a.wg.Done()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-a.ticker.C:
-				fmt.Println("Tick: loading feeds…")
-				feeds, err := a.repo.ListFeeds(5) // Take 5 oldest feeds
-				if err != nil {
-					fmt.Printf("error loading feeds: %v\n", err)
-					continue
-				}
-				for _, feed := range feeds {
-					select {
-					case a.jobs <- feed:
-					case <-ctx.Done():
-						return
-					}
-				}
-			}
+	go a.dispatch(dispatchCtx, a.ticker)
+
+	return nil
+}
+
+// RunOnce processes the given feeds with the worker pool, waits for all of them and stops the pool.
+// It is meant for cron-like runs and cannot be combined with Start
+func (a *Aggregator) RunOnce(ctx context.Context, feeds []domain.Feed) ([]domain.FeedResult, error) {
+	a.mu.Lock()
+	if a.running {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("aggregator is already running")
+	}
+	if a.workersNum <= 0 {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("number of workers should be greater than 0")
+	}
+
+	a.ctx, a.cancel = context.WithCancel(ctx)
+	a.stopDispatch = a.cancel
+	a.results = make(chan domain.FeedResult, len(feeds))
+	a.running = true
+	a.spawnWorkers(min(a.workersNum, max(len(feeds), 1)))
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		a.running = false
+		a.retireWorkers(len(a.workers))
+		a.cancel()
+		a.mu.Unlock()
+		a.wg.Wait()
+
+		// The results of a later Start have nobody to read them
+		a.mu.Lock()
+		a.results = nil
+		a.mu.Unlock()
+
+		// Cron runs have no ticks, so the finished jobs are dropped here
+		if ctx.Err() == nil {
+			purgeCtx, cancel := context.WithTimeout(ctx, queryTimeout)
+			a.purgeJobs(purgeCtx)
+			cancel()
 		}
 	}()
 
-	return nil
+	wanted := make(map[string]bool, len(feeds))
+	for _, feed := range feeds {
+		if err := a.enqueue(ctx, feed, domain.PriorityNormal); err != nil {
+			return nil, err
+		}
+		wanted[feed.ID] = true
+	}
+
+	// Workers may also pick jobs left by earlier runs, only the requested feeds are reported
+	results := make([]domain.FeedResult, 0, len(feeds))
+	for len(wanted) > 0 {
+		select {
+		case res := <-a.results:
+			if wanted[res.Feed.ID] {
+				delete(wanted, res.Feed.ID)
+				results = append(results, res)
+			}
+		case <-ctx.Done():
+			return results, ctx.Err()
+		}
+	}
+	return results, nil
+}
+
+// dispatch queues every feed on each tick until ctx is cancelled
+func (a *Aggregator) dispatch(ctx context.Context, ticker *time.Ticker) {
+	defer a.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if a.IsPaused() {
+				logger.Debug("Tick skipped: fetching is paused")
+				continue
+			}
+			a.mu.Lock()
+			a.lastTick = time.Now()
+			a.mu.Unlock()
+			fmt.Println("Tick: loading feeds…")
+			a.queueAllFeeds(ctx)
+		}
+	}
+}
+
+// queueAllFeeds queues every feed and drops old finished jobs, all within queryTimeout
+func (a *Aggregator) queueAllFeeds(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	feeds, err := a.repo.ListFeeds(ctx, domain.FeedFilter{}, 0)
+	if err != nil {
+		fmt.Printf("error loading feeds: %v\n", err)
+		return
+	}
+	interval := a.GetCurrentInterval()
+
+	// Feeds with an interval of their own are fetched on the tick closest to it being due.
+	// Feeds which are still queued or being fetched are not queued twice
+	now := time.Now()
+	for _, feed := range feeds {
+		if feed.Interval > 0 && !feed.Due(now.Add(interval/2), interval) {
+			continue
+		}
+		if err := a.enqueue(ctx, feed, domain.PriorityNormal); err != nil {
+			fmt.Printf("error queueing feed %s: %v\n", feed.Name, err)
+		}
+	}
+
+	a.purgeJobs(ctx)
 }
 
-func (a *Aggregator) Stop() {
-	lock.Release()
+// purgeJobs drops the done and failed jobs that finished more than jobRetention ago
+func (a *Aggregator) purgeJobs(ctx context.Context) {
+	if err := a.repo.PurgeFetchJobs(ctx, time.Now().Add(-jobRetention)); err != nil {
+		logger.Error("failed to purge finished fetch jobs", "error", err)
+	}
+}
+
+// Stop stops dispatching and lets the workers finish the feeds they are fetching.
+// Fetches still running after drainTimeout are cancelled, their jobs go back to the queue
+// and their feeds are returned
+func (a *Aggregator) Stop(drainTimeout time.Duration) []domain.Feed {
 	a.mu.Lock()
-	close(a.stopWorkers)
-	a.cancel()
+	if !a.running {
+		a.mu.Unlock()
+		return nil
+	}
+	a.running = false
+	a.stopDispatch()
 	a.ticker.Stop()
+
+	// Every worker quits after its current job
+	a.retireWorkers(len(a.workers))
 	a.mu.Unlock()
 
-	a.wg.Wait()
-	a.running = false
+	drained := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(drainTimeout):
+		logger.Debug("Drain deadline reached, cancelling in-flight fetches", "timeout", drainTimeout)
+		a.cancel()
+		<-drained
+	}
+	a.cancel()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.interrupted
 }
 
+// SetInterval changes the tick interval, restarting the ticker if it is running
 func (a *Aggregator) SetInterval(d time.Duration) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	// Only stop the ticker if it's running
-	if a.running {
-		fmt.Printf("Changing interval from %v to %v\n", a.interval, d)
-		a.ticker.Stop() // Stop the old ticker
-
-		// Create a new ticker with the new duration
-		a.ticker = time.NewTicker(d)
+	if a.interval == d {
+		return
+	}
+	if a.running && a.ticker != nil {
+		a.ticker.Reset(d)
 	}
-
 	a.interval = d
-	fmt.Printf("Interval changed to %v\n", d)
+	logger.Debug("The ticker has restarted with new interval", "interval", d)
 }
 
 // --- Worker function ---
-func (a *Aggregator) Worker(ctx context.Context, id int) {
+func (a *Aggregator) Worker(ctx context.Context, id int, quit <-chan struct{}) {
 	defer a.wg.Done()
+
+	poll := time.NewTicker(jobPollInterval)
+	defer poll.Stop()
+
 	for {
+		// A worker that was asked to quit must not pick up another job
 		select {
 		case <-ctx.Done():
 			return
-		case <-a.stopWorkers:
-			fmt.Printf("[worker %d] stopping\n", id)
+		case <-quit:
+			logger.Debug("Worker stopped", "worker", id)
 			return
-		case feed := <-a.jobs:
-			// Fetch and parse RSS for the feed
-			fmt.Printf("[worker %d] fetching %s (%s)\n", id, feed.Name, feed.URL)
+		default:
+		}
 
-			parsed, err := rss.FetchAndParse(feed.URL)
-			if err != nil {
-				fmt.Printf("[worker %d] error fetching %s: %v\n", id, feed.Name, err)
-				continue
-			}
+		claimCtx, cancel := context.WithTimeout(ctx, queryTimeout)
+		job, err := a.repo.ClaimFetchJob(claimCtx, jobLease)
+		cancel()
+		if err == nil {
+			a.processJob(ctx, id, job)
+			continue
+		}
+		if err != domain.ErrNoJobs && ctx.Err() == nil {
+			fmt.Printf("[worker %d] error picking a job: %v\n", id, err)
+		}
 
-			// Process each article and save it to the database
-			for _, item := range parsed.Channel.Items {
-				article := domain.Article{
-					FeedID:      feed.ID,
-					Title:       item.Title,
-					Link:        item.Link,
-					Description: item.Description,
-					CreatedAt:   time.Now(),
-					UpdatedAt:   time.Now(),
-				}
-
-				// Parse pubDate if possible
-				parsedTime, err := rss.ParsePubDate(item.PubDate)
-				if err == nil {
-					article.PublishedAt = parsedTime
-				} else {
-					fmt.Printf("[worker %d] warning: could not parse date '%s': %v\n", id, item.PubDate, err)
-					article.PublishedAt = time.Now()
-				}
-
-				// Save to DB
-				err = a.repo.AddArticle(article)
-				if err != nil {
-					fmt.Printf("[worker %d] skipping article '%s': %v\n", id, article.Title, err)
-				} else {
-					fmt.Printf("[worker %d] saved: %s\n", id, article.Title)
-				}
-			}
+		// Nothing to do until new jobs are queued
+		select {
+		case <-ctx.Done():
+			return
+		case <-quit:
+			logger.Debug("Worker stopped", "worker", id)
+			return
+		case <-a.wake:
+		case <-poll.C:
+		}
+	}
+}
 
-			// Update the feed timestamp after processing
-			feed.UpdatedAt = time.Now()
-			if err := a.repo.UpdateFeedTimestamp(feed.ID, feed.UpdatedAt); err != nil {
-				fmt.Printf("[worker %d] failed to update feed timestamp: %v\n", id, err)
-			}
+// processJob fetches the job's feed, stores its articles and records the outcome in the queue
+func (a *Aggregator) processJob(ctx context.Context, id int, job domain.FetchJob) {
+	feed := job.Feed
+	fmt.Printf("[worker %d] fetching %s (%s)\n", id, feed.Name, feed.URL)
+	a.setBusy(1)
+	defer a.setBusy(-1)
+
+	// A fetch must end before its lease does, otherwise another worker could pick the same job
+	fetchCtx, cancel := context.WithTimeout(ctx, jobLease)
+	defer cancel()
+
+	// Queue bookkeeping must happen even when the fetch was cancelled by shutdown
+	queueCtx, cancelQueue := context.WithTimeout(context.WithoutCancel(ctx), queryTimeout)
+	defer cancelQueue()
+
+	stats, err := FetchFeed(fetchCtx, a.repo, feed)
+	if err != nil && ctx.Err() != nil {
+		// Cancelled by shutdown, the job is given back to the queue for the next run
+		fmt.Printf("[worker %d] interrupted while fetching %s\n", id, feed.Name)
+		a.mu.Lock()
+		a.interrupted = append(a.interrupted, feed)
+		a.mu.Unlock()
+		if err := a.repo.ReleaseFetchJob(queueCtx, job.ID); err != nil {
+			fmt.Printf("[worker %d] failed to requeue job %d: %v\n", id, job.ID, err)
+		}
+		return
+	}
+	a.mu.Lock()
+	results := a.results
+	a.mu.Unlock()
+	if results != nil {
+		select {
+		case results <- domain.FeedResult{Feed: feed, Stats: stats, Err: err}:
+		case <-ctx.Done():
 		}
 	}
+	if err != nil {
+		fmt.Printf("[worker %d] error fetching %s: %v\n", id, feed.Name, err)
+		a.recordFetch(true)
+		if err := a.repo.FailFetchJob(queueCtx, job.ID, err.Error()); err != nil {
+			fmt.Printf("[worker %d] failed to mark job %d as failed: %v\n", id, job.ID, err)
+		}
+		return
+	}
+
+	fmt.Printf("[worker %d] %s: %d new, %d updated, %d skipped\n", id, feed.Name, stats.New, stats.Updated, stats.Skipped)
+	a.recordFetch(false)
+	if err := a.repo.CompleteFetchJob(queueCtx, job.ID); err != nil {
+		fmt.Printf("[worker %d] failed to mark job %d as done: %v\n", id, job.ID, err)
+	}
 }
 
 func (a *Aggregator) GetCurrentInterval() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	return a.interval
 }
 
-func (a *Aggregator) SetCurrentInterval(interval time.Duration) {
-	a.interval = interval
+func (a *Aggregator) GetWorkersNum() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.workersNum
 }
 
-func (a *Aggregator) RestartTicker() {
+// UpdateWorkers grows or shrinks the pool by exactly the difference with the current size.
+// Removed workers finish the feed they are processing before they exit.
+func (a *Aggregator) UpdateWorkers(workersNum int) error {
+	if workersNum <= 0 {
+		return fmt.Errorf("number of workers should be greater than 0")
+	}
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if a.ticker != nil {
-		a.ticker.Reset(a.interval)
+	oldWorkersNum := a.workersNum
+	a.workersNum = workersNum
+	if !a.running {
+		return nil
 	}
 
-	logger.Debug("The ticker has restarted with new interval", "interval", a.interval)
+	if workersNum > oldWorkersNum {
+		a.spawnWorkers(workersNum - oldWorkersNum)
+	} else if workersNum < oldWorkersNum {
+		a.retireWorkers(oldWorkersNum - workersNum)
+	}
+	logger.Debug("Worker pool resized", "from", oldWorkersNum, "to", workersNum)
+	return nil
 }
 
-func (a *Aggregator) GetWorkersNum() int {
-	return a.workersNum
-}
+// spawnWorkers starts n new workers. Caller must hold a.mu
+func (a *Aggregator) spawnWorkers(n int) {
+	for i := 0; i < n; i++ {
+		id := a.nextWorkerID
+		a.nextWorkerID++
 
-func (a *Aggregator) SetWorkersNum(workersNum int) {
-	a.workersNum = workersNum
+		quit := make(chan struct{})
+		a.workers[id] = quit
+		a.wg.Add(1)
+		go a.Worker(a.ctx, id, quit)
+	}
 }
 
-func (a *Aggregator) UpdateWorkers(ctx context.Context, oldWorkersNum int, workersNum int) {
-	// fmt.Println("sdfsf")
-	if workersNum > oldWorkersNum {
-		// fmt.Println("hll")
-		for i := 0; i < workersNum-oldWorkersNum; i++ {
-			a.wg.Add(1)
-			go a.Worker(ctx, oldWorkersNum+i)
-		}
-	} else if workersNum < oldWorkersNum {
-		fmt.Println("sdf")
-		for i := 0; i < oldWorkersNum; i++ {
-			a.stopWorkers <- i
+// retireWorkers asks the n most recently started workers to stop. Caller must hold a.mu
+func (a *Aggregator) retireWorkers(n int) {
+	for id := a.nextWorkerID - 1; id >= 0 && n > 0; id-- {
+		quit, ok := a.workers[id]
+		if !ok {
+			continue
 		}
+		close(quit)
+		delete(a.workers, id)
+		n--
+	}
+}
 
-		for i := 0; i < workersNum; i++ {
-			a.wg.Add(1)
-			go a.Worker(ctx, i)
+// Pause makes ticks skip job dispatch while workers stay alive.
+// A zero until keeps fetching paused until Resume is called
+func (a *Aggregator) Pause(until time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pause = domain.PauseState{Paused: true, Until: until}
+}
+
+func (a *Aggregator) Resume() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pause = domain.PauseState{}
+}
+
+func (a *Aggregator) IsPaused() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.pausedLocked()
+}
+
+// PausedUntil returns when the current pause ends, zero if not paused or paused indefinitely
+func (a *Aggregator) PausedUntil() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.pausedLocked() {
+		return time.Time{}
+	}
+	return a.pause.Until
+}
+
+// pausedLocked clears an expired pause. Caller must hold a.mu
+func (a *Aggregator) pausedLocked() bool {
+	if a.pause.Paused && !a.pause.Active(time.Now()) {
+		a.pause = domain.PauseState{}
+		logger.Debug("Pause has expired, fetching is resumed")
+	}
+	return a.pause.Paused
+}
+
+// Enqueue queues a feed ahead of the regular jobs without waiting for the next tick
+func (a *Aggregator) Enqueue(ctx context.Context, feed domain.Feed) error {
+	return a.enqueue(ctx, feed, domain.PriorityHigh)
+}
+
+func (a *Aggregator) enqueue(ctx context.Context, feed domain.Feed, priority int) error {
+	if err := a.repo.EnqueueFetchJob(ctx, feed.ID, priority); err != nil {
+		return fmt.Errorf("failed to queue feed %s: %w", feed.Name, err)
+	}
+
+	// Waking an idle worker, if the wake channel is full all of them are busy anyway
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (a *Aggregator) setBusy(delta int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.busyWorkers += delta
+}
+
+func (a *Aggregator) recordFetch(failed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fetchLog = append(a.fetchLog, fetchEvent{at: time.Now(), failed: failed})
+	a.trimFetchLog()
+}
+
+// trimFetchLog drops events older than statsWindow. Caller must hold a.mu
+func (a *Aggregator) trimFetchLog() {
+	cutoff := time.Now().Add(-statsWindow)
+	i := 0
+	for i < len(a.fetchLog) && a.fetchLog[i].at.Before(cutoff) {
+		i++
+	}
+	a.fetchLog = a.fetchLog[i:]
+}
+
+func (a *Aggregator) Stats(ctx context.Context) domain.AggregatorStats {
+	queued, err := a.repo.CountQueuedFetchJobs(ctx)
+	if err != nil {
+		logger.Error("failed to count queued fetch jobs", "error", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.trimFetchLog()
+	stats := domain.AggregatorStats{
+		Interval:    a.interval,
+		Workers:     a.workersNum,
+		BusyWorkers: a.busyWorkers,
+		QueueDepth:  queued,
+		Paused:      a.pausedLocked(),
+		PausedUntil: a.pause.Until,
+		LastTick:    a.lastTick,
+	}
+	for _, e := range a.fetchLog {
+		if e.failed {
+			stats.FailedLastHour++
+		} else {
+			stats.FetchedLastHour++
 		}
 	}
-	fmt.Println("bsdfs")
+	return stats
 }
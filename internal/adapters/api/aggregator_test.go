@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"RSSHub/internal/adapters/memory"
+	"RSSHub/internal/domain"
+	"RSSHub/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
+
+// stubRepo has an empty job queue and panics on any other call; the hourly ticker never fires in these tests
+type stubRepo struct{ domain.Repository }
+
+func (stubRepo) ClaimFetchJob(context.Context, time.Duration) (domain.FetchJob, error) {
+	return domain.FetchJob{}, domain.ErrNoJobs
+}
+func (stubRepo) CountQueuedFetchJobs(context.Context) (int, error) { return 0, nil }
+
+// poolSize returns how many workers the pool has right now
+func poolSize(a *Aggregator) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.workers)
+}
+
+func TestUpdateWorkersConcurrently(t *testing.T) {
+	a := NewAggregator(time.Hour, 2, stubRepo{})
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Stop(time.Second)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if err := a.UpdateWorkers(1 + (g+i)%8); err != nil {
+					t.Error(err)
+				}
+			}
+		}(g)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			a.SetInterval(time.Duration(1+i%3) * time.Minute)
+			a.Pause(time.Time{})
+			a.Resume()
+			a.Stats(context.Background())
+		}
+	}()
+	wg.Wait()
+
+	if err := a.UpdateWorkers(5); err != nil {
+		t.Fatal(err)
+	}
+	if got := poolSize(a); got != 5 {
+		t.Errorf("pool has %d workers, want 5", got)
+	}
+	if got := a.GetWorkersNum(); got != 5 {
+		t.Errorf("GetWorkersNum() = %d, want 5", got)
+	}
+
+	if err := a.UpdateWorkers(0); err == nil {
+		t.Error("UpdateWorkers(0) succeeded")
+	}
+	if got := poolSize(a); got != 5 {
+		t.Errorf("pool has %d workers after a rejected resize, want 5", got)
+	}
+}
+
+func TestStopWhileResizing(t *testing.T) {
+	a := NewAggregator(time.Hour, 3, stubRepo{})
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				a.UpdateWorkers(1 + (g+i)%6)
+				a.Stats(context.Background())
+			}
+		}(g)
+	}
+	a.Stop(time.Second)
+	wg.Wait()
+
+	// A stopped pool only remembers the size for the next start
+	if err := a.UpdateWorkers(4); err != nil {
+		t.Fatal(err)
+	}
+	if got := poolSize(a); got != 0 {
+		t.Errorf("stopped pool has %d workers, want 0", got)
+	}
+	if got := a.GetWorkersNum(); got != 4 {
+		t.Errorf("GetWorkersNum() = %d, want 4", got)
+	}
+	if interrupted := a.Stop(time.Second); interrupted != nil {
+		t.Errorf("second Stop returned %v", interrupted)
+	}
+}
+
+// purgeRecorder remembers the cutoffs PurgeFetchJobs was called with
+type purgeRecorder struct {
+	domain.Repository
+	mu      sync.Mutex
+	cutoffs []time.Time
+}
+
+func (r *purgeRecorder) PurgeFetchJobs(ctx context.Context, finishedBefore time.Time) error {
+	r.mu.Lock()
+	r.cutoffs = append(r.cutoffs, finishedBefore)
+	r.mu.Unlock()
+	return r.Repository.PurgeFetchJobs(ctx, finishedBefore)
+}
+
+func TestRunOncePurgesFinishedJobs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>T</title>` +
+			`<item><title>One</title><link>http://example.com/1</link></item></channel></rss>`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	repo := &purgeRecorder{Repository: memory.NewMemoryRepository()}
+	now := time.Now()
+	if err := repo.AddFeed(ctx, domain.Feed{Name: "t", URL: srv.URL, CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	feed, err := repo.ListFeedByName(ctx, "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewAggregator(time.Hour, 2, repo)
+	results, err := a.RunOnce(ctx, []domain.Feed{feed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Err != nil || results[0].Stats.New != 1 {
+		t.Fatalf("RunOnce() = %+v, want one feed with one new article", results)
+	}
+
+	if len(repo.cutoffs) != 1 {
+		t.Fatalf("PurgeFetchJobs called %d times, want once", len(repo.cutoffs))
+	}
+	if age := time.Since(repo.cutoffs[0]); age < jobRetention || age > jobRetention+time.Minute {
+		t.Errorf("finished jobs purged up to %v ago, want %v", age, jobRetention)
+	}
+	if got := poolSize(a); got != 0 {
+		t.Errorf("pool has %d workers after RunOnce, want 0", got)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.results != nil {
+		t.Error("results channel kept after RunOnce, later workers would send to it")
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"RSSHub/internal/adapters/rss"
+	"RSSHub/internal/domain"
+	"RSSHub/internal/domain/utils"
+	"RSSHub/pkg/logger"
+)
+
+// FetchFeed downloads a single feed and stores its articles together with the feed timestamp
+// in one transaction. It is shared by the aggregator workers and the one-shot CLI commands.
+// When ctx is cancelled nothing is stored and ctx.Err() is returned
+func FetchFeed(ctx context.Context, repo domain.Repository, feed domain.Feed) (domain.IngestStats, error) {
+	parsed, err := rss.FetchAndParse(ctx, feed.URL)
+	if err != nil {
+		return domain.IngestStats{}, err
+	}
+
+	articles := make([]domain.Article, 0, len(parsed.Channel.Items))
+	for _, item := range parsed.Channel.Items {
+		article := domain.Article{
+			FeedID:      feed.ID,
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        strings.TrimSpace(item.GUID),
+			Description: item.Description,
+			Content:     item.Content,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+
+		// Parse pubDate if possible
+		parsedTime, err := rss.ParsePubDate(item.PubDate)
+		if err == nil {
+			article.PublishedAt = parsedTime
+		} else {
+			logger.Debug("could not parse article date", "feed", feed.Name, "pubDate", item.PubDate, "error", err)
+			article.PublishedAt = time.Now()
+		}
+		articles = append(articles, article)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return domain.IngestStats{}, err
+	}
+
+	// Save to DB
+	stats, err := repo.IngestFeed(ctx, feed.ID, articles, time.Now())
+	if err != nil {
+		return domain.IngestStats{}, fmt.Errorf("failed to store articles: %w", err)
+	}
+
+	// The articles are stored, a failure here only leaves the old metadata in place
+	meta := FeedMeta(ctx, parsed, feed.URL, feed.Meta)
+	if meta != feed.Meta {
+		if err := repo.UpdateFeedMeta(ctx, feed.ID, meta); err != nil {
+			logger.Error("failed to store feed metadata", "feed", feed.Name, "error", err)
+		}
+	}
+	return stats, nil
+}
+
+// iconRecheckInterval is how often a site without a known favicon is checked again
+const iconRecheckInterval = 24 * time.Hour
+
+// FeedMeta takes the metadata of a parsed feed. The favicon is looked up again when the
+// site link changes, or once a day while none is known
+func FeedMeta(ctx context.Context, parsed *domain.RSSFeed, feedURL string, previous domain.FeedMeta) domain.FeedMeta {
+	ch := parsed.Channel
+	meta := domain.FeedMeta{
+		Title:         strings.TrimSpace(ch.Title),
+		SiteURL:       parsed.SiteLink(),
+		Description:   utils.StripHTML(ch.Description),
+		Language:      strings.TrimSpace(ch.Language),
+		ImageURL:      strings.TrimSpace(ch.Image.URL),
+		IconURL:       previous.IconURL,
+		IconCheckedAt: previous.IconCheckedAt,
+	}
+
+	now := time.Now()
+	if meta.SiteURL == previous.SiteURL && (meta.IconURL != "" || now.Sub(meta.IconCheckedAt) < iconRecheckInterval) {
+		return meta
+	}
+
+	// Feeds without a site link get the icon of the host serving them
+	site := meta.SiteURL
+	if site == "" {
+		u, err := url.Parse(feedURL)
+		if err != nil {
+			return meta
+		}
+		site = (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String()
+	}
+
+	icon, err := rss.DiscoverIcon(ctx, site)
+	if err != nil {
+		logger.Debug("could not discover favicon", "site", site, "error", err)
+	}
+	meta.IconURL = icon
+	meta.IconCheckedAt = now
+	return meta
+}
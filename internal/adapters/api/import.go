@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"RSSHub/internal/adapters/rss"
+	"RSSHub/internal/domain"
+)
+
+const (
+	// importWorkers is how many feeds are validated at once
+	importWorkers = 8
+	// importFeedTimeout bounds validating a single feed
+	importFeedTimeout = 30 * time.Second
+)
+
+type ImportStatus string
+
+const (
+	ImportAdded   ImportStatus = "added"
+	ImportSkipped ImportStatus = "skipped"
+	ImportFailed  ImportStatus = "failed"
+)
+
+// ImportResult tells what happened to one feed of an imported subscription list
+type ImportResult struct {
+	Feed   domain.Feed
+	Status ImportStatus
+	Reason string
+}
+
+// ImportFeeds adds the feeds of a subscription list, skipping the URLs already subscribed to.
+// With validate every feed is fetched first, concurrently, and only the readable ones are added.
+// Names taken by other feeds get a numeric suffix
+func ImportFeeds(ctx context.Context, repo domain.Repository, feeds []domain.Feed, validate bool) ([]ImportResult, error) {
+	existing, err := repo.ListFeeds(ctx, domain.FeedFilter{}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feeds: %w", err)
+	}
+	byURL := make(map[string]string, len(existing))
+	names := make(map[string]bool, len(existing))
+	for _, f := range existing {
+		byURL[f.URL] = f.Name
+		names[f.Name] = true
+	}
+
+	results := make([]ImportResult, len(feeds))
+	listed := make(map[string]bool, len(feeds))
+	var pending []int
+	for i, feed := range feeds {
+		results[i].Feed = feed
+		switch name, ok := byURL[feed.URL]; {
+		case feed.URL == "":
+			results[i].Status, results[i].Reason = ImportFailed, "no feed URL"
+		case ok:
+			results[i].Status, results[i].Reason = ImportSkipped, fmt.Sprintf("already subscribed as '%s'", name)
+		case listed[feed.URL]:
+			results[i].Status, results[i].Reason = ImportSkipped, "listed twice in the file"
+		default:
+			listed[feed.URL] = true
+			pending = append(pending, i)
+		}
+	}
+
+	if validate {
+		validateFeeds(ctx, results, pending)
+	}
+
+	now := time.Now()
+	for _, i := range pending {
+		res := &results[i]
+		if res.Status == ImportFailed {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		res.Feed.Name = uniqueName(names, res.Feed.Name)
+		res.Feed.CreatedAt, res.Feed.UpdatedAt = now, now
+		if err := repo.AddFeed(ctx, res.Feed); err != nil {
+			res.Status, res.Reason = ImportFailed, err.Error()
+			continue
+		}
+		names[res.Feed.Name] = true
+		res.Status = ImportAdded
+	}
+	return results, nil
+}
+
+// validateFeeds fetches the pending feeds with importWorkers at a time. Readable feeds get
+// their metadata, the others are marked as failed
+func validateFeeds(ctx context.Context, results []ImportResult, pending []int) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, importWorkers)
+	for _, i := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(res *ImportResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			feedCtx, cancel := context.WithTimeout(ctx, importFeedTimeout)
+			defer cancel()
+			parsed, err := rss.FetchAndParse(feedCtx, res.Feed.URL)
+			switch {
+			case err != nil:
+				res.Status, res.Reason = ImportFailed, err.Error()
+			case parsed.Channel.Title == "":
+				res.Status, res.Reason = ImportFailed, "not an RSS feed this app can read"
+			default:
+				res.Feed.Meta = FeedMeta(feedCtx, parsed, res.Feed.URL, res.Feed.Meta)
+			}
+		}(&results[i])
+	}
+	wg.Wait()
+}
+
+// uniqueName returns name, or name-2, name-3... when it is taken
+func uniqueName(taken map[string]bool, name string) string {
+	if !taken[name] {
+		return name
+	}
+	for n := 2; ; n++ {
+		if candidate := fmt.Sprintf("%s-%d", name, n); !taken[candidate] {
+			return candidate
+		}
+	}
+}
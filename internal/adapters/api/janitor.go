@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"RSSHub/internal/domain"
+	"RSSHub/pkg/logger"
+)
+
+// janitorInterval is how often the fetch process applies the retention rules
+const janitorInterval = time.Hour
+
+// PruneFeeds applies the retention rules to every feed: the global policy, with the feed's own
+// rules taking precedence. It is shared by the janitor and `rsshub prune`
+func PruneFeeds(ctx context.Context, repo domain.Repository, global domain.RetentionPolicy, dryRun bool) ([]domain.PruneResult, error) {
+	feeds, err := repo.ListFeeds(ctx, domain.FeedFilter{}, 0)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := repo.ListFeedRetention(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []domain.PruneResult
+	now := time.Now()
+	for _, feed := range feeds {
+		policy := global.Merge(policies[feed.ID])
+		if policy.IsZero() {
+			continue
+		}
+
+		articles, err := repo.PruneArticles(ctx, feed.ID, policy, now, dryRun)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, domain.PruneResult{Feed: feed, Policy: policy, Articles: articles})
+	}
+	return results, nil
+}
+
+// Janitor prunes old articles in the background of `rsshub fetch`
+type Janitor struct {
+	repo   domain.Repository
+	global domain.RetentionPolicy
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func NewJanitor(repo domain.Repository, global domain.RetentionPolicy) *Janitor {
+	return &Janitor{repo: repo, global: global}
+}
+
+// Start prunes right away and then every janitorInterval until ctx is cancelled or Stop is called
+func (j *Janitor) Start(ctx context.Context) {
+	ctx, j.cancel = context.WithCancel(ctx)
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		ticker := time.NewTicker(janitorInterval)
+		defer ticker.Stop()
+
+		for {
+			j.prune(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits for a running prune to finish
+func (j *Janitor) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.wg.Wait()
+}
+
+func (j *Janitor) prune(ctx context.Context) {
+	results, err := PruneFeeds(ctx, j.repo, j.global, false)
+	if err != nil && ctx.Err() == nil {
+		logger.Error("error pruning articles", "error", err)
+	}
+	for _, res := range results {
+		if len(res.Articles) > 0 {
+			logger.Info("Pruned articles", "feed", res.Feed.Name, "count", len(res.Articles), "policy", res.Policy.String())
+		}
+	}
+}
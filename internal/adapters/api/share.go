@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	"RSSHub/internal/domain"
@@ -26,41 +27,16 @@ func NewShareVar(repo domain.Repository, agg domain.Aggregator) *ShareVariables
 func (share *ShareVariables) UpdateShare(dbInterval time.Duration, workersNum int, ctx context.Context) {
 	share.ticker = time.NewTicker(dbInterval)
 
-	share.repo.SetDefaultCliIntervalAndWorkersNum(config.GetEnvInterval(), workersNum)
+	initCtx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	share.repo.SetDefaultCliIntervalAndWorkersNum(initCtx, config.GetEnvInterval(), workersNum)
 
 	go func() {
 		for {
 			select {
 			case <-share.ticker.C:
-				// Getting interval value from db
-				dbInterval, err := share.repo.FetchCliInterval()
-				if err != sql.ErrNoRows {
-					logger.Debug("Getting interval from db", "interval", dbInterval)
-				}
-				workersNum, err := share.repo.FetchWorkersNumber()
-				if err != sql.ErrNoRows {
-					logger.Debug("Getting workers number from db", "workers", workersNum)
-				}
-
-				interval, err := utils.ParseIntervalToDuration(dbInterval)
-				if err != nil {
-					logger.Error("error parsing interval that came from db", "error", err, "interval", interval)
-					continue
-				}
-
-				// Interval Update
-				if share.agg.GetCurrentInterval() != interval {
-					share.agg.SetCurrentInterval(interval)
-					share.agg.RestartTicker()
-					logger.Debug("Current interval after update", "interval", share.agg.GetCurrentInterval())
-				}
-
-				// Worker number update
-				oldWorkersNum := share.agg.GetWorkersNum()
-				if oldWorkersNum != workersNum {
-					share.agg.SetWorkersNum(workersNum)
-					share.agg.UpdateWorkers(ctx, oldWorkersNum, workersNum)
-					logger.Debug("Current workers number after update", "workers number", share.agg.GetWorkersNum())
+				if err := share.Sync(ctx); err != nil {
+					logger.Error("error syncing shared settings", "error", err)
 				}
 			case <-ctx.Done():
 				return
@@ -69,6 +45,53 @@ func (share *ShareVariables) UpdateShare(dbInterval time.Duration, workersNum in
 	}()
 }
 
+// Sync reads the interval and workers number from db and applies them to the aggregator
+func (share *ShareVariables) Sync(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// Getting interval value from db
+	dbInterval, err := share.repo.FetchCliInterval(ctx)
+	if err != sql.ErrNoRows {
+		logger.Debug("Getting interval from db", "interval", dbInterval)
+	}
+	workersNum, err := share.repo.FetchWorkersNumber(ctx)
+	if err != sql.ErrNoRows {
+		logger.Debug("Getting workers number from db", "workers", workersNum)
+	}
+
+	interval, err := utils.ParseIntervalToDuration(dbInterval)
+	if err != nil {
+		return fmt.Errorf("error parsing interval %q that came from db: %w", dbInterval, err)
+	}
+
+	// Interval Update
+	if share.agg.GetCurrentInterval() != interval {
+		share.agg.SetInterval(interval)
+		logger.Debug("Current interval after update", "interval", share.agg.GetCurrentInterval())
+	}
+
+	// Worker number update
+	if share.agg.GetWorkersNum() != workersNum {
+		if err := share.agg.UpdateWorkers(workersNum); err != nil {
+			return fmt.Errorf("error updating workers number %d that came from db: %w", workersNum, err)
+		}
+		logger.Debug("Current workers number after update", "workers number", share.agg.GetWorkersNum())
+	}
+
+	// Pause update
+	pause, err := share.repo.FetchPauseState(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting pause state from db: %w", err)
+	}
+	if pause.Active(time.Now()) {
+		share.agg.Pause(pause.Until)
+	} else if share.agg.IsPaused() {
+		share.agg.Resume()
+	}
+	return nil
+}
+
 func (share *ShareVariables) Stop() {
 	share.ticker.Stop()
 }
@@ -0,0 +1,338 @@
+// Package backup writes the whole state of the app to a portable archive and restores it.
+// It only goes through domain.Repository, so a backup taken from one storage backend
+// can be restored into any other.
+//
+// The archive is a gzipped tar holding, in this order:
+//
+//	manifest.json   format name and version, creation time and counts
+//	settings.jsonl  the fetch interval, worker count and pause state, when they are set
+//	feeds.jsonl     one feed per line with its folder, tags, interval, metadata and retention
+//	articles.jsonl  one article per line with its read and starred state
+//	pruned.jsonl    the link and GUID of each article the retention rules removed, so that
+//	                a restored database does not fetch them again
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"RSSHub/internal/domain"
+)
+
+const (
+	// Format names the archives this package writes
+	Format = "rsshub-backup"
+	// Version is bumped whenever the records change in a way older readers cannot follow
+	Version = 1
+)
+
+const (
+	manifestFile = "manifest.json"
+	settingsFile = "settings.jsonl"
+	feedsFile    = "feeds.jsonl"
+	articlesFile = "articles.jsonl"
+	prunedFile   = "pruned.jsonl"
+)
+
+// pageSize is how many articles are read or written at once
+const pageSize = 500
+
+// Manifest describes an archive
+type Manifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Feeds     int       `json:"feeds"`
+	Articles  int       `json:"articles"`
+	Pruned    int       `json:"pruned"`
+}
+
+// The records below are the archive format. They are kept apart from the domain types
+// so that a change to those does not silently change the format
+
+type settingsRecord struct {
+	Interval    string    `json:"interval"`
+	Workers     int       `json:"workers"`
+	Paused      bool      `json:"paused"`
+	PausedUntil time.Time `json:"paused_until"`
+}
+
+type feedRecord struct {
+	Name      string     `json:"name"`
+	URL       string     `json:"url"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Folder    string     `json:"folder,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	Interval  string     `json:"interval,omitempty"`
+	Meta      metaRecord `json:"meta"`
+	Retention struct {
+		MaxArticles int    `json:"max_articles,omitempty"`
+		MaxAge      string `json:"max_age,omitempty"`
+	} `json:"retention"`
+}
+
+type metaRecord struct {
+	Title         string    `json:"title,omitempty"`
+	SiteURL       string    `json:"site_url,omitempty"`
+	Description   string    `json:"description,omitempty"`
+	Language      string    `json:"language,omitempty"`
+	ImageURL      string    `json:"image_url,omitempty"`
+	IconURL       string    `json:"icon_url,omitempty"`
+	IconCheckedAt time.Time `json:"icon_checked_at"`
+}
+
+type articleRecord struct {
+	// Feed is the name of the feed, ids are not kept across databases
+	Feed        string    `json:"feed"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	GUID        string    `json:"guid,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Content     string    `json:"content,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Read        bool      `json:"read,omitempty"`
+	Starred     bool      `json:"starred,omitempty"`
+}
+
+type prunedRecord struct {
+	Feed     string    `json:"feed"`
+	Link     string    `json:"link"`
+	GUID     string    `json:"guid,omitempty"`
+	PrunedAt time.Time `json:"pruned_at"`
+}
+
+// Write stores every feed, article, pruned article and setting of the repository in an archive
+func Write(ctx context.Context, repo domain.Repository, w io.Writer, now time.Time) (Manifest, error) {
+	manifest := Manifest{Format: Format, Version: Version, CreatedAt: now.UTC()}
+
+	// tar needs the size of a file before its content, so the records are spooled first
+	settings, err := spool(func(enc *json.Encoder) error { return writeSettings(ctx, repo, enc) })
+	if err != nil {
+		return manifest, fmt.Errorf("failed to back up settings: %w", err)
+	}
+	defer removeSpool(settings)
+
+	feeds, err := repo.ListFeeds(ctx, domain.FeedFilter{}, 0)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to list feeds: %w", err)
+	}
+	manifest.Feeds = len(feeds)
+	feedsSpool, err := spool(func(enc *json.Encoder) error { return writeFeeds(ctx, repo, feeds, enc) })
+	if err != nil {
+		return manifest, fmt.Errorf("failed to back up feeds: %w", err)
+	}
+	defer removeSpool(feedsSpool)
+
+	articlesSpool, err := spool(func(enc *json.Encoder) error {
+		manifest.Articles, err = writeArticles(ctx, repo, feeds, enc)
+		return err
+	})
+	if err != nil {
+		return manifest, fmt.Errorf("failed to back up articles: %w", err)
+	}
+	defer removeSpool(articlesSpool)
+
+	prunedSpool, err := spool(func(enc *json.Encoder) error {
+		manifest.Pruned, err = writePruned(ctx, repo, feeds, enc)
+		return err
+	})
+	if err != nil {
+		return manifest, fmt.Errorf("failed to back up pruned articles: %w", err)
+	}
+	defer removeSpool(prunedSpool)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	data = append(data, '\n')
+	if err := addFile(tw, manifestFile, int64(len(data)), now, bytes.NewReader(data)); err != nil {
+		return manifest, err
+	}
+	for _, f := range []struct {
+		name  string
+		spool *os.File
+	}{{settingsFile, settings}, {feedsFile, feedsSpool}, {articlesFile, articlesSpool}, {prunedFile, prunedSpool}} {
+		info, err := f.spool.Stat()
+		if err != nil {
+			return manifest, err
+		}
+		if _, err := f.spool.Seek(0, io.SeekStart); err != nil {
+			return manifest, err
+		}
+		if err := addFile(tw, f.name, info.Size(), now, f.spool); err != nil {
+			return manifest, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return manifest, err
+	}
+	return manifest, gz.Close()
+}
+
+func writeSettings(ctx context.Context, repo domain.Repository, enc *json.Encoder) error {
+	interval, err := repo.FetchCliInterval(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The fetcher never ran, there is nothing to keep
+		return nil
+	} else if err != nil {
+		return err
+	}
+	workers, err := repo.FetchWorkersNumber(ctx)
+	if err != nil {
+		return err
+	}
+	pause, err := repo.FetchPauseState(ctx)
+	if err != nil {
+		return err
+	}
+	return enc.Encode(settingsRecord{Interval: interval, Workers: workers, Paused: pause.Paused, PausedUntil: pause.Until.UTC()})
+}
+
+func writeFeeds(ctx context.Context, repo domain.Repository, feeds []domain.Feed, enc *json.Encoder) error {
+	retention, err := repo.ListFeedRetention(ctx)
+	if err != nil {
+		return err
+	}
+	for _, feed := range feeds {
+		if err := enc.Encode(toFeedRecord(feed, retention[feed.ID])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeArticles pages through the timeline of every feed, so that the articles of a feed
+// come together and the memory used stays bounded
+func writeArticles(ctx context.Context, repo domain.Repository, feeds []domain.Feed, enc *json.Encoder) (int, error) {
+	count := 0
+	for _, feed := range feeds {
+		q := domain.TimelineQuery{FeedNames: []string{feed.Name}, Limit: pageSize}
+		for {
+			entries, err := repo.Timeline(ctx, q)
+			if err != nil {
+				return count, err
+			}
+			for _, e := range entries {
+				if err := enc.Encode(toArticleRecord(e)); err != nil {
+					return count, err
+				}
+			}
+			count += len(entries)
+			if len(entries) < pageSize {
+				break
+			}
+			cursor := domain.CursorOf(entries[len(entries)-1].Article)
+			q.After = &cursor
+		}
+	}
+	return count, nil
+}
+
+func writePruned(ctx context.Context, repo domain.Repository, feeds []domain.Feed, enc *json.Encoder) (int, error) {
+	count := 0
+	for _, feed := range feeds {
+		pruned, err := repo.ListPrunedArticles(ctx, feed.ID)
+		if err != nil {
+			return count, err
+		}
+		for _, p := range pruned {
+			rec := prunedRecord{Feed: feed.Name, Link: p.Link, GUID: p.GUID, PrunedAt: p.PrunedAt.UTC()}
+			if err := enc.Encode(rec); err != nil {
+				return count, err
+			}
+		}
+		count += len(pruned)
+	}
+	return count, nil
+}
+
+func toFeedRecord(feed domain.Feed, policy domain.RetentionPolicy) feedRecord {
+	rec := feedRecord{
+		Name:      feed.Name,
+		URL:       feed.URL,
+		CreatedAt: feed.CreatedAt.UTC(),
+		UpdatedAt: feed.UpdatedAt.UTC(),
+		Folder:    feed.Folder,
+		Tags:      feed.Tags,
+		Meta: metaRecord{
+			Title:         feed.Meta.Title,
+			SiteURL:       feed.Meta.SiteURL,
+			Description:   feed.Meta.Description,
+			Language:      feed.Meta.Language,
+			ImageURL:      feed.Meta.ImageURL,
+			IconURL:       feed.Meta.IconURL,
+			IconCheckedAt: feed.Meta.IconCheckedAt.UTC(),
+		},
+	}
+	if feed.Interval > 0 {
+		rec.Interval = feed.Interval.String()
+	}
+	rec.Retention.MaxArticles = policy.MaxArticles
+	if policy.MaxAge > 0 {
+		rec.Retention.MaxAge = policy.MaxAge.String()
+	}
+	return rec
+}
+
+func toArticleRecord(e domain.TimelineEntry) articleRecord {
+	a := e.Article
+	return articleRecord{
+		Feed:        e.FeedName,
+		Title:       a.Title,
+		Link:        a.Link,
+		GUID:        a.GUID,
+		Description: a.Description,
+		Content:     a.Content,
+		PublishedAt: a.PublishedAt.UTC(),
+		CreatedAt:   a.CreatedAt.UTC(),
+		UpdatedAt:   a.UpdatedAt.UTC(),
+		Read:        a.Read,
+		Starred:     a.Starred,
+	}
+}
+
+// spool writes JSON lines to a temporary file, which the caller removes with removeSpool
+func spool(write func(enc *json.Encoder) error) (*os.File, error) {
+	file, err := os.CreateTemp("", "rsshub-backup-*.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(file)
+	enc.SetEscapeHTML(false)
+	if err := write(enc); err != nil {
+		removeSpool(file)
+		return nil, err
+	}
+	return file, nil
+}
+
+func removeSpool(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
+
+func addFile(tw *tar.Writer, name string, size int64, modTime time.Time, content io.Reader) error {
+	header := &tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: modTime, Format: tar.FormatPAX}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, content)
+	return err
+}
@@ -0,0 +1,297 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"RSSHub/internal/adapters/backup"
+	"RSSHub/internal/adapters/memory"
+	"RSSHub/internal/domain"
+)
+
+var base = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// articleState is what a restore must carry over for an article
+type articleState struct {
+	feed    string
+	title   string
+	guid    string
+	read    bool
+	starred bool
+}
+
+// seed fills repo with two feeds, three articles, one pruned article and the settings of `set-interval 2d`
+func seed(t *testing.T, ctx context.Context, repo domain.Repository) {
+	t.Helper()
+	feeds := []domain.Feed{
+		{
+			Name: "go", URL: "http://go.example/rss", CreatedAt: base, UpdatedAt: base.Add(time.Hour),
+			Folder: "Tech/Go", Tags: []string{"go", "news"}, Interval: 3 * time.Hour,
+			Meta: domain.FeedMeta{Title: "The Go Blog", SiteURL: "http://go.example", IconURL: "http://go.example/favicon.ico"},
+		},
+		{Name: "misc", URL: "http://misc.example/rss", CreatedAt: base, UpdatedAt: base},
+	}
+	for _, feed := range feeds {
+		if err := repo.AddFeed(ctx, feed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.SetFeedRetention(ctx, "go", domain.RetentionPolicy{MaxArticles: 50, MaxAge: 30 * 24 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, links := range map[string][]string{"go": {"http://go.example/1", "http://go.example/2"}, "misc": {"http://misc.example/0", "http://misc.example/1"}} {
+		feed, err := repo.ListFeedByName(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var articles []domain.Article
+		for i, link := range links {
+			articles = append(articles, domain.Article{
+				CreatedAt: base, UpdatedAt: base, Title: "Title of " + link, Link: link, GUID: "tag:" + link,
+				Content: "<p>body</p>", PublishedAt: base.Add(time.Duration(i) * time.Minute), FeedID: feed.ID,
+			})
+		}
+		if _, err := repo.IngestFeed(ctx, feed.ID, articles, feed.UpdatedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	misc, err := repo.ListFeedByName(ctx, "misc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.PruneArticles(ctx, misc.ID, domain.RetentionPolicy{MaxArticles: 1}, base, false); err != nil {
+		t.Fatal(err)
+	}
+	setFlags(t, ctx, repo, "http://go.example/1", true, true)
+	setFlags(t, ctx, repo, "http://misc.example/1", true, false)
+
+	if err := repo.SetDefaultCliIntervalAndWorkersNum(ctx, "2d", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetPauseState(ctx, domain.PauseState{Paused: true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setFlags(t *testing.T, ctx context.Context, repo domain.Repository, link string, read, starred bool) {
+	t.Helper()
+	for _, a := range articles(t, ctx, repo) {
+		if a.Article.Link != link {
+			continue
+		}
+		if err := repo.SetArticleRead(ctx, a.Article.ID, read); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.SetArticleStarred(ctx, a.Article.ID, starred); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	t.Fatalf("no article %s", link)
+}
+
+func articles(t *testing.T, ctx context.Context, repo domain.Repository) []domain.TimelineEntry {
+	t.Helper()
+	entries, err := repo.Timeline(ctx, domain.TimelineQuery{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func states(t *testing.T, ctx context.Context, repo domain.Repository) map[string]articleState {
+	t.Helper()
+	got := make(map[string]articleState)
+	for _, e := range articles(t, ctx, repo) {
+		got[e.Article.Link] = articleState{feed: e.FeedName, title: e.Article.Title, guid: e.Article.GUID, read: e.Article.Read, starred: e.Article.Starred}
+	}
+	return got
+}
+
+func archive(t *testing.T, ctx context.Context, repo domain.Repository) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	manifest, err := backup.Write(ctx, repo, &buf, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Feeds != 2 || manifest.Articles != 3 || manifest.Pruned != 1 {
+		t.Fatalf("manifest counts %d feeds, %d articles and %d pruned, want 2, 3 and 1", manifest.Feeds, manifest.Articles, manifest.Pruned)
+	}
+	return buf.Bytes()
+}
+
+func restore(t *testing.T, ctx context.Context, repo domain.Repository, data []byte, strategy backup.Strategy) backup.Result {
+	t.Helper()
+	result, err := backup.Restore(ctx, repo, bytes.NewReader(data), strategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := memory.NewMemoryRepository()
+	seed(t, ctx, src)
+	data := archive(t, ctx, src)
+
+	dst := memory.NewMemoryRepository()
+	result := restore(t, ctx, dst, data, backup.Merge)
+	if result.FeedsAdded != 2 || result.Articles.New != 3 || result.Pruned != 1 || !result.Settings {
+		t.Fatalf("restore result = %+v, want 2 feeds, 3 new articles, 1 pruned and the settings", result)
+	}
+
+	// Both feeds were added at the same time, so they are compared by name
+	byName := func(a, b domain.Feed) int { return strings.Compare(a.Name, b.Name) }
+	want, _ := src.ListFeeds(ctx, domain.FeedFilter{}, 0)
+	got, _ := dst.ListFeeds(ctx, domain.FeedFilter{}, 0)
+	slices.SortFunc(want, byName)
+	slices.SortFunc(got, byName)
+	if len(got) != len(want) {
+		t.Fatalf("restored %d feeds, want %d", len(got), len(want))
+	}
+	for i := range want {
+		w, g := want[i], got[i]
+		w.ID, g.ID = "", ""
+		if w.Name != g.Name || w.URL != g.URL || w.Folder != g.Folder || !slices.Equal(w.Tags, g.Tags) ||
+			w.Interval != g.Interval || w.Meta != g.Meta || !w.CreatedAt.Equal(g.CreatedAt) || !w.UpdatedAt.Equal(g.UpdatedAt) {
+			t.Errorf("restored feed\n%+v\nwant\n%+v", g, w)
+		}
+	}
+
+	policies, _ := dst.ListFeedRetention(ctx)
+	goFeed, _ := dst.ListFeedByName(ctx, "go")
+	if p := policies[goFeed.ID]; p.MaxArticles != 50 || p.MaxAge != 30*24*time.Hour {
+		t.Errorf("restored retention = %+v", p)
+	}
+
+	if w, g := states(t, ctx, src), states(t, ctx, dst); !maps.Equal(w, g) {
+		t.Errorf("restored articles\n%v\nwant\n%v", g, w)
+	}
+
+	srcMisc, _ := src.ListFeedByName(ctx, "misc")
+	dstMisc, _ := dst.ListFeedByName(ctx, "misc")
+	wantPruned, _ := src.ListPrunedArticles(ctx, srcMisc.ID)
+	gotPruned, _ := dst.ListPrunedArticles(ctx, dstMisc.ID)
+	if !slices.EqualFunc(gotPruned, wantPruned, func(g, w domain.PrunedArticle) bool {
+		return g.Link == w.Link && g.GUID == w.GUID && g.PrunedAt.Equal(w.PrunedAt)
+	}) {
+		t.Errorf("restored pruned articles %+v, want %+v", gotPruned, wantPruned)
+	}
+
+	interval, _ := dst.FetchCliInterval(ctx)
+	workers, _ := dst.FetchWorkersNumber(ctx)
+	pause, _ := dst.FetchPauseState(ctx)
+	if interval != "2d" || workers != 4 || !pause.Paused {
+		t.Errorf("restored settings: interval %q, %d workers, paused %v", interval, workers, pause.Paused)
+	}
+}
+
+func TestConflictStrategies(t *testing.T) {
+	ctx := context.Background()
+	src := memory.NewMemoryRepository()
+	seed(t, ctx, src)
+	data := archive(t, ctx, src)
+
+	// The database has moved on since the backup: go/1 was unstarred, go/2 read,
+	// and the settings and the folder of go were changed
+	changed := func(t *testing.T) domain.Repository {
+		repo := memory.NewMemoryRepository()
+		restore(t, ctx, repo, data, backup.Merge)
+		setFlags(t, ctx, repo, "http://go.example/1", true, false)
+		setFlags(t, ctx, repo, "http://go.example/2", true, false)
+		feed, _ := repo.ListFeedByName(ctx, "go")
+		feed.Folder = "Elsewhere"
+		if err := repo.UpdateFeed(ctx, "go", feed); err != nil {
+			t.Fatal(err)
+		}
+		repo.SetInterval(ctx, "5m")
+		return repo
+	}
+
+	tests := []struct {
+		strategy backup.Strategy
+		folder   string
+		interval string
+		go1      articleState
+		go2      articleState
+	}{
+		// Merge only ever marks articles, so go/1 is starred again and go/2 stays read
+		{backup.Merge, "Elsewhere", "5m", articleState{read: true, starred: true}, articleState{read: true}},
+		{backup.Skip, "Elsewhere", "5m", articleState{read: true}, articleState{read: true}},
+		{backup.Replace, "Tech/Go", "2d", articleState{read: true, starred: true}, articleState{}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			repo := changed(t)
+			result := restore(t, ctx, repo, data, tt.strategy)
+			if result.FeedsAdded != 0 || result.Articles.New != 0 {
+				t.Errorf("restore result = %+v, want nothing added", result)
+			}
+
+			feed, _ := repo.ListFeedByName(ctx, "go")
+			if feed.Folder != tt.folder {
+				t.Errorf("folder = %q, want %q", feed.Folder, tt.folder)
+			}
+			if interval, _ := repo.FetchCliInterval(ctx); interval != tt.interval {
+				t.Errorf("interval = %q, want %q", interval, tt.interval)
+			}
+
+			got := states(t, ctx, repo)
+			for link, want := range map[string]articleState{"http://go.example/1": tt.go1, "http://go.example/2": tt.go2} {
+				if g := got[link]; g.read != want.read || g.starred != want.starred {
+					t.Errorf("%s: read %v, starred %v; want read %v, starred %v", link, g.read, g.starred, want.read, want.starred)
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreFindsFeedsByURL(t *testing.T) {
+	ctx := context.Background()
+	src := memory.NewMemoryRepository()
+	seed(t, ctx, src)
+	data := archive(t, ctx, src)
+
+	repo := memory.NewMemoryRepository()
+	if err := repo.AddFeed(ctx, domain.Feed{Name: "golang", URL: "http://go.example/rss", CreatedAt: base, UpdatedAt: base}); err != nil {
+		t.Fatal(err)
+	}
+	result := restore(t, ctx, repo, data, backup.Merge)
+	if result.FeedsAdded != 1 || result.FeedsMerged != 1 {
+		t.Fatalf("restore result = %+v, want 1 feed added and 1 merged", result)
+	}
+	if got := states(t, ctx, repo)["http://go.example/1"]; got.feed != "golang" || !got.starred {
+		t.Errorf("go/1 restored as %+v, want it starred in golang", got)
+	}
+}
+
+func TestRestoreRefusesNewerFormat(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	manifest, _ := json.Marshal(backup.Manifest{Format: backup.Format, Version: backup.Version + 1})
+	tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0o644, Size: int64(len(manifest))})
+	tw.Write(manifest)
+	tw.Close()
+	gz.Close()
+
+	repo := memory.NewMemoryRepository()
+	_, err := backup.Restore(context.Background(), repo, &buf, backup.Merge)
+	if err == nil || !strings.Contains(err.Error(), "format version") {
+		t.Fatalf("Restore() error = %v, want a format version error", err)
+	}
+	if feeds, _ := repo.ListFeeds(context.Background(), domain.FeedFilter{}, 0); len(feeds) != 0 {
+		t.Errorf("restored %d feeds from a refused archive", len(feeds))
+	}
+}
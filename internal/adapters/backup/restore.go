@@ -0,0 +1,472 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"RSSHub/internal/domain"
+	"RSSHub/internal/domain/utils"
+)
+
+// Strategy tells what a restore does with a feed of the archive that is already in the
+// database, under the same name or the same URL
+type Strategy string
+
+const (
+	// Skip leaves the feed and its articles as they are
+	Skip Strategy = "skip"
+	// Merge keeps the feed's settings, adds its missing articles and marks the articles
+	// that are read or starred in the archive. Nothing is marked unread or unstarred
+	Merge Strategy = "merge"
+	// Replace takes the feed's settings, and the read and starred state of its articles,
+	// from the archive
+	Replace Strategy = "replace"
+)
+
+func ParseStrategy(value string) (Strategy, error) {
+	switch s := Strategy(value); s {
+	case Skip, Merge, Replace:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown conflict strategy %q, use skip, merge or replace", value)
+	}
+}
+
+// Result counts what a restore did
+type Result struct {
+	Manifest      Manifest
+	FeedsAdded    int
+	FeedsMerged   int
+	FeedsReplaced int
+	FeedsSkipped  int
+	Articles      domain.IngestStats
+	// Pruned counts the pruned articles of the archive that are remembered again
+	Pruned int
+	// Settings tells whether the fetch settings of the archive were applied. They are when
+	// the database has none yet, or with Replace
+	Settings bool
+}
+
+// target is where the articles of a feed of the archive go
+type target struct {
+	id   string
+	name string
+	// fetchedAt is kept as the time the feed was last fetched
+	fetchedAt time.Time
+	skip      bool
+}
+
+type articleFlags struct {
+	read    bool
+	starred bool
+}
+
+type restorer struct {
+	repo     domain.Repository
+	strategy Strategy
+	result   Result
+	// targets maps the feed names of the archive to the feeds of the database
+	targets map[string]*target
+	// flags holds the read and starred state to apply, by database feed name and link
+	flags map[string]map[string]articleFlags
+}
+
+// Restore loads an archive written by Write. Feeds that are not in the database are added
+// with all their articles, the others are handled as the strategy says. A restore that
+// fails half-way keeps what was restored so far, running it again with Merge completes it
+func Restore(ctx context.Context, repo domain.Repository, r io.Reader, strategy Strategy) (Result, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Result{}, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	res := &restorer{
+		repo:     repo,
+		strategy: strategy,
+		targets:  make(map[string]*target),
+		flags:    make(map[string]map[string]articleFlags),
+	}
+
+	header, err := tr.Next()
+	if err != nil {
+		return Result{}, fmt.Errorf("not a backup archive: %w", err)
+	}
+	if header.Name != manifestFile {
+		return Result{}, fmt.Errorf("not a backup archive: %s does not come first", manifestFile)
+	}
+	if err := json.NewDecoder(tr).Decode(&res.result.Manifest); err != nil {
+		return Result{}, fmt.Errorf("failed to read %s: %w", manifestFile, err)
+	}
+	switch m := res.result.Manifest; {
+	case m.Format != Format:
+		return res.result, fmt.Errorf("not a backup archive: unknown format %q", m.Format)
+	case m.Version < 1 || m.Version > Version:
+		return res.result, fmt.Errorf("the archive has format version %d, this version of rsshub reads up to %d", m.Version, Version)
+	}
+
+	feedsRestored := false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return res.result, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		dec := json.NewDecoder(tr)
+		switch header.Name {
+		case settingsFile:
+			err = res.restoreSettings(ctx, dec)
+		case feedsFile:
+			err = res.restoreFeeds(ctx, dec)
+			feedsRestored = true
+		case articlesFile:
+			if !feedsRestored {
+				return res.result, fmt.Errorf("invalid archive: %s comes before %s", articlesFile, feedsFile)
+			}
+			err = res.restoreArticles(ctx, dec)
+		case prunedFile:
+			if !feedsRestored {
+				return res.result, fmt.Errorf("invalid archive: %s comes before %s", prunedFile, feedsFile)
+			}
+			err = res.restorePruned(ctx, dec)
+		}
+		if err != nil {
+			return res.result, fmt.Errorf("failed to restore %s: %w", header.Name, err)
+		}
+	}
+
+	if err := res.applyFlags(ctx); err != nil {
+		return res.result, fmt.Errorf("failed to restore read and starred state: %w", err)
+	}
+	return res.result, nil
+}
+
+func (res *restorer) restoreSettings(ctx context.Context, dec *json.Decoder) error {
+	for {
+		var rec settingsRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		_, err := res.repo.FetchCliInterval(ctx)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil && res.strategy != Replace {
+			continue
+		}
+		// The interval is stored as set-interval took it, e.g. 2d
+		if _, err := utils.ParseIntervalToDuration(rec.Interval); err != nil {
+			return fmt.Errorf("invalid fetch interval %q: %w", rec.Interval, err)
+		}
+		if err := res.repo.SetDefaultCliIntervalAndWorkersNum(ctx, rec.Interval, rec.Workers); err != nil {
+			return err
+		}
+		if err := res.repo.SetPauseState(ctx, domain.PauseState{Paused: rec.Paused, Until: rec.PausedUntil}); err != nil {
+			return err
+		}
+		res.result.Settings = true
+	}
+}
+
+func (res *restorer) restoreFeeds(ctx context.Context, dec *json.Decoder) error {
+	existing, err := res.repo.ListFeeds(ctx, domain.FeedFilter{}, 0)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]domain.Feed, len(existing))
+	byURL := make(map[string]domain.Feed, len(existing))
+	for _, f := range existing {
+		byName[f.Name] = f
+		if _, ok := byURL[f.URL]; !ok {
+			byURL[f.URL] = f
+		}
+	}
+
+	for {
+		var rec feedRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		feed, policy, err := rec.toFeed()
+		if err != nil {
+			return err
+		}
+		if _, ok := res.targets[feed.Name]; ok {
+			return fmt.Errorf("feed %q is listed twice", feed.Name)
+		}
+
+		current, ok := byName[feed.Name]
+		if !ok {
+			current, ok = byURL[feed.URL]
+		}
+		if !ok {
+			added, err := res.addFeed(ctx, feed, policy)
+			if err != nil {
+				return fmt.Errorf("feed %q: %w", feed.Name, err)
+			}
+			byName[added.Name], byURL[added.URL] = added, added
+			res.targets[feed.Name] = &target{id: added.ID, name: added.Name, fetchedAt: feed.UpdatedAt}
+			res.result.FeedsAdded++
+			continue
+		}
+
+		switch res.strategy {
+		case Skip:
+			res.targets[feed.Name] = &target{skip: true}
+			res.result.FeedsSkipped++
+			continue
+		case Replace:
+			if err := res.replaceFeed(ctx, current, feed, policy); err != nil {
+				return fmt.Errorf("feed %q: %w", feed.Name, err)
+			}
+			res.result.FeedsReplaced++
+		default:
+			res.result.FeedsMerged++
+		}
+		res.targets[feed.Name] = &target{id: current.ID, name: current.Name, fetchedAt: current.UpdatedAt}
+	}
+}
+
+func (res *restorer) addFeed(ctx context.Context, feed domain.Feed, policy domain.RetentionPolicy) (domain.Feed, error) {
+	if err := res.repo.AddFeed(ctx, feed); err != nil {
+		return domain.Feed{}, err
+	}
+	added, err := res.repo.ListFeedByName(ctx, feed.Name)
+	if err != nil {
+		return domain.Feed{}, err
+	}
+	if !policy.IsZero() {
+		if err := res.repo.SetFeedRetention(ctx, feed.Name, policy); err != nil {
+			return domain.Feed{}, err
+		}
+	}
+	return added, nil
+}
+
+// replaceFeed gives the existing feed the settings of the archive. It keeps its name,
+// which differs from the archive's when the feed was found by its URL
+func (res *restorer) replaceFeed(ctx context.Context, current, feed domain.Feed, policy domain.RetentionPolicy) error {
+	feed.Name = current.Name
+	if err := res.repo.UpdateFeed(ctx, current.Name, feed); err != nil {
+		return err
+	}
+	if len(current.Tags) > 0 {
+		if err := res.repo.UntagFeed(ctx, current.Name, current.Tags); err != nil {
+			return err
+		}
+	}
+	if len(feed.Tags) > 0 {
+		if err := res.repo.TagFeed(ctx, current.Name, feed.Tags); err != nil {
+			return err
+		}
+	}
+	if err := res.repo.UpdateFeedMeta(ctx, current.ID, feed.Meta); err != nil {
+		return err
+	}
+	return res.repo.SetFeedRetention(ctx, current.Name, policy)
+}
+
+// restoreArticles ingests the articles in batches of consecutive articles of the same feed
+func (res *restorer) restoreArticles(ctx context.Context, dec *json.Decoder) error {
+	var batch []domain.Article
+	var batchTarget *target
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() { batch = batch[:0] }()
+		if batchTarget.skip {
+			res.result.Articles.Skipped += len(batch)
+			return nil
+		}
+		stats, err := res.repo.IngestFeed(ctx, batchTarget.id, batch, batchTarget.fetchedAt)
+		if err != nil {
+			return err
+		}
+		res.result.Articles.New += stats.New
+		res.result.Articles.Updated += stats.Updated
+		res.result.Articles.Skipped += stats.Skipped
+		return nil
+	}
+
+	for {
+		var rec articleRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		t, ok := res.targets[rec.Feed]
+		if !ok {
+			return fmt.Errorf("article %q belongs to feed %q, which is not in the archive", rec.Link, rec.Feed)
+		}
+
+		if t != batchTarget || len(batch) == pageSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			batchTarget = t
+		}
+		batch = append(batch, rec.toArticle())
+
+		if !t.skip && (rec.Read || rec.Starred || res.strategy == Replace) {
+			if res.flags[t.name] == nil {
+				res.flags[t.name] = make(map[string]articleFlags)
+			}
+			res.flags[t.name][rec.Link] = articleFlags{read: rec.Read, starred: rec.Starred}
+		}
+	}
+	return flush()
+}
+
+// restorePruned remembers the pruned articles of the feeds that are not skipped, in batches
+// of consecutive entries of the same feed
+func (res *restorer) restorePruned(ctx context.Context, dec *json.Decoder) error {
+	var batch []domain.PrunedArticle
+	var batchTarget *target
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() { batch = batch[:0] }()
+		if batchTarget.skip {
+			return nil
+		}
+		if err := res.repo.AddPrunedArticles(ctx, batchTarget.id, batch); err != nil {
+			return err
+		}
+		res.result.Pruned += len(batch)
+		return nil
+	}
+
+	for {
+		var rec prunedRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if rec.Link == "" {
+			return fmt.Errorf("a pruned article of feed %q has no link", rec.Feed)
+		}
+		t, ok := res.targets[rec.Feed]
+		if !ok {
+			return fmt.Errorf("pruned article %q belongs to feed %q, which is not in the archive", rec.Link, rec.Feed)
+		}
+
+		if t != batchTarget || len(batch) == pageSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			batchTarget = t
+		}
+		batch = append(batch, domain.PrunedArticle{Link: rec.Link, GUID: rec.GUID, PrunedAt: rec.PrunedAt})
+	}
+	return flush()
+}
+
+// applyFlags sets the read and starred state of the restored articles, which ingesting
+// does not carry over
+func (res *restorer) applyFlags(ctx context.Context) error {
+	for feedName, flags := range res.flags {
+		q := domain.TimelineQuery{FeedNames: []string{feedName}, Limit: pageSize}
+		for {
+			entries, err := res.repo.Timeline(ctx, q)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				a := e.Article
+				f, ok := flags[a.Link]
+				if !ok {
+					continue
+				}
+				if res.strategy != Replace {
+					f.read, f.starred = f.read || a.Read, f.starred || a.Starred
+				}
+				if f.read != a.Read {
+					if err := res.repo.SetArticleRead(ctx, a.ID, f.read); err != nil {
+						return err
+					}
+				}
+				if f.starred != a.Starred {
+					if err := res.repo.SetArticleStarred(ctx, a.ID, f.starred); err != nil {
+						return err
+					}
+				}
+			}
+			if len(entries) < pageSize {
+				break
+			}
+			cursor := domain.CursorOf(entries[len(entries)-1].Article)
+			q.After = &cursor
+		}
+	}
+	return nil
+}
+
+func (rec feedRecord) toFeed() (domain.Feed, domain.RetentionPolicy, error) {
+	var policy domain.RetentionPolicy
+	if rec.Name == "" || rec.URL == "" {
+		return domain.Feed{}, policy, fmt.Errorf("a feed has no name or URL")
+	}
+
+	feed := domain.Feed{
+		Name:      rec.Name,
+		URL:       rec.URL,
+		CreatedAt: rec.CreatedAt,
+		UpdatedAt: rec.UpdatedAt,
+		Folder:    rec.Folder,
+		Tags:      rec.Tags,
+		Meta: domain.FeedMeta{
+			Title:         rec.Meta.Title,
+			SiteURL:       rec.Meta.SiteURL,
+			Description:   rec.Meta.Description,
+			Language:      rec.Meta.Language,
+			ImageURL:      rec.Meta.ImageURL,
+			IconURL:       rec.Meta.IconURL,
+			IconCheckedAt: rec.Meta.IconCheckedAt,
+		},
+	}
+	var err error
+	if rec.Interval != "" {
+		if feed.Interval, err = time.ParseDuration(rec.Interval); err != nil {
+			return feed, policy, fmt.Errorf("feed %q: invalid interval %q", rec.Name, rec.Interval)
+		}
+	}
+	policy.MaxArticles = rec.Retention.MaxArticles
+	if rec.Retention.MaxAge != "" {
+		if policy.MaxAge, err = time.ParseDuration(rec.Retention.MaxAge); err != nil {
+			return feed, policy, fmt.Errorf("feed %q: invalid retention age %q", rec.Name, rec.Retention.MaxAge)
+		}
+	}
+	return feed, policy, nil
+}
+
+func (rec articleRecord) toArticle() domain.Article {
+	return domain.Article{
+		CreatedAt:   rec.CreatedAt,
+		UpdatedAt:   rec.UpdatedAt,
+		Title:       rec.Title,
+		Link:        rec.Link,
+		GUID:        rec.GUID,
+		Description: rec.Description,
+		Content:     rec.Content,
+		PublishedAt: rec.PublishedAt,
+	}
+}
@@ -0,0 +1,37 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ErrDaemonNotRunning is returned when nothing listens on the control socket.
+var ErrDaemonNotRunning = errors.New("fetch daemon is not running")
+
+const clientTimeout = 5 * time.Second
+
+// Send delivers a request to the daemon listening on socket and waits for its response
+func Send(socket string, req Request) (Response, error) {
+	conn, err := net.DialTimeout("unix", socket, clientTimeout)
+	if err != nil {
+		return Response{}, ErrDaemonNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(clientTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	if !resp.OK {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,61 @@
+package control
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SocketPath returns the Unix domain socket of the `fetch` daemon that uses the given storage,
+// so that daemons of different databases do not share a socket. It lives in $XDG_RUNTIME_DIR,
+// or in a directory of the current user under the system temp dir when that is not set
+func SocketPath(storage string) string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("rsshub-%d", os.Getuid()))
+	}
+	sum := sha256.Sum256([]byte(storage))
+	return filepath.Join(dir, fmt.Sprintf("rsshub-%x.sock", sum[:6]))
+}
+
+// Supported commands of the control protocol
+const (
+	CmdStatus      = "status"
+	CmdSetInterval = "set-interval"
+	CmdSetWorkers  = "set-workers"
+	CmdPause       = "pause"
+	CmdResume      = "resume"
+	CmdFetchNow    = "fetch-now"
+	CmdReload      = "reload"
+)
+
+// Request is a single JSON message sent by the CLI to the daemon.
+type Request struct {
+	Command string            `json:"command"`
+	Args    map[string]string `json:"args,omitempty"`
+}
+
+// Response is the daemon's answer to a Request.
+type Response struct {
+	OK      bool    `json:"ok"`
+	Error   string  `json:"error,omitempty"`
+	Message string  `json:"message,omitempty"`
+	Status  *Status `json:"status,omitempty"`
+}
+
+// Status describes the current state of the running daemon.
+type Status struct {
+	PID             int       `json:"pid"`
+	StartedAt       time.Time `json:"started_at"`
+	Interval        string    `json:"interval"`
+	Workers         int       `json:"workers"`
+	BusyWorkers     int       `json:"busy_workers"`
+	QueueDepth      int       `json:"queue_depth"`
+	Paused          bool      `json:"paused"`
+	PausedUntil     time.Time `json:"paused_until"`
+	LastTick        time.Time `json:"last_tick"`
+	FetchedLastHour int       `json:"fetched_last_hour"`
+	FailedLastHour  int       `json:"failed_last_hour"`
+}
@@ -0,0 +1,263 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"RSSHub/internal/domain"
+	"RSSHub/internal/domain/utils"
+	"RSSHub/pkg/logger"
+)
+
+// Server accepts control requests from the CLI and applies them to the running aggregator.
+type Server struct {
+	agg   domain.Aggregator
+	share domain.ShareVariables
+	repo  domain.Repository
+
+	path      string
+	listener  *net.UnixListener
+	startedAt time.Time
+	wg        sync.WaitGroup
+}
+
+func NewServer(agg domain.Aggregator, share domain.ShareVariables, repo domain.Repository, path string) *Server {
+	return &Server{agg: agg, share: share, repo: repo, path: path}
+}
+
+// Start opens the control socket and serves requests until Stop is called.
+// Only the user running the daemon may connect to the socket
+func (s *Server) Start(ctx context.Context) error {
+	if err := prepareSocketDir(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+
+	// A socket left behind by a crashed daemon would make Listen fail, but one that still
+	// answers belongs to a running daemon and must be left alone
+	if conn, err := net.DialTimeout("unix", s.path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("another fetch daemon is already listening on %s", s.path)
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket %s: %w", s.path, err)
+	}
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: s.path, Net: "unix"})
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.path, err)
+	}
+	// Stop removes the socket itself, and only when no other daemon took it over
+	listener.SetUnlinkOnClose(false)
+	if err := os.Chmod(s.path, 0o600); err != nil {
+		listener.Close()
+		os.Remove(s.path)
+		return fmt.Errorf("failed to restrict access to %s: %w", s.path, err)
+	}
+	s.listener = listener
+	s.startedAt = time.Now()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				logger.Error("control socket accept failed", "error", err)
+				continue
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.handle(ctx, conn)
+			}()
+		}
+	}()
+
+	logger.Debug("Control socket is listening", "path", s.path)
+	return nil
+}
+
+func (s *Server) Stop() {
+	if s.listener == nil {
+		return
+	}
+	s.listener.Close()
+	s.wg.Wait()
+	if conn, err := net.DialTimeout("unix", s.path, time.Second); err == nil {
+		// Another daemon listens on the path now
+		conn.Close()
+		return
+	}
+	_ = os.Remove(s.path)
+}
+
+// prepareSocketDir creates the directory of the socket for the current user only.
+// An existing directory must belong to that user and not be writable by anyone else,
+// otherwise another user could replace the socket
+func prepareSocketDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("socket directory %s is not a directory", dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("socket directory %s belongs to another user", dir)
+	}
+	if info.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("socket directory %s is writable by other users (mode %v)", dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(clientTimeout))
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		logger.Error("could not decode control request", "error", err)
+		return
+	}
+	logger.Debug("Control request received", "command", req.Command, "args", req.Args)
+
+	// The client gives up after clientTimeout, so there is no point in working longer
+	ctx, cancel := context.WithTimeout(ctx, clientTimeout)
+	defer cancel()
+
+	resp, err := s.dispatch(ctx, req)
+	if err != nil {
+		resp = Response{OK: false, Error: err.Error()}
+	} else {
+		resp.OK = true
+	}
+
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		logger.Error("could not write control response", "error", err)
+	}
+}
+
+func (s *Server) dispatch(ctx context.Context, req Request) (Response, error) {
+	switch req.Command {
+	case CmdStatus:
+		return Response{Status: s.status(ctx)}, nil
+
+	case CmdSetInterval:
+		duration := req.Args["duration"]
+		interval, err := utils.ParseIntervalToDuration(duration)
+		if err != nil {
+			return Response{}, err
+		}
+		if err := s.repo.SetInterval(ctx, duration); err != nil {
+			return Response{}, fmt.Errorf("error updating interval in db: %w", err)
+		}
+		s.agg.SetInterval(interval)
+		return Response{Message: fmt.Sprintf("The interval of fetching feeds changed to %v", interval)}, nil
+
+	case CmdSetWorkers:
+		workersNum, err := strconv.Atoi(req.Args["workers"])
+		if err != nil || workersNum <= 0 || workersNum > 10 {
+			return Response{}, fmt.Errorf("number of workers should be greater than 0 and less than or equal to 10")
+		}
+		if err := s.repo.SetWorkers(ctx, workersNum); err != nil {
+			return Response{}, fmt.Errorf("error updating workers in db: %w", err)
+		}
+		if err := s.agg.UpdateWorkers(workersNum); err != nil {
+			return Response{}, err
+		}
+		return Response{Message: fmt.Sprintf("The number of workers have changed to %v", workersNum)}, nil
+
+	case CmdPause:
+		state := domain.PauseState{Paused: true}
+		if duration := req.Args["for"]; duration != "" {
+			d, err := utils.ParseIntervalToDuration(duration)
+			if err != nil {
+				return Response{}, err
+			}
+			state.Until = time.Now().Add(d)
+		}
+		if err := s.repo.SetPauseState(ctx, state); err != nil {
+			return Response{}, fmt.Errorf("error saving pause state in db: %w", err)
+		}
+		s.agg.Pause(state.Until)
+		if state.Until.IsZero() {
+			return Response{Message: "Fetching is paused until resumed"}, nil
+		}
+		return Response{Message: fmt.Sprintf("Fetching is paused until %s", state.Until.Format("2006-01-02 15:04:05"))}, nil
+
+	case CmdResume:
+		if err := s.repo.SetPauseState(ctx, domain.PauseState{}); err != nil {
+			return Response{}, fmt.Errorf("error saving pause state in db: %w", err)
+		}
+		s.agg.Resume()
+		return Response{Message: "Fetching is resumed"}, nil
+
+	case CmdFetchNow:
+		var feeds []domain.Feed
+		if req.Args["name"] == "" {
+			// All feeds, or the ones of a folder or tag
+			filter := domain.FeedFilter{Folder: req.Args["folder"], Tag: req.Args["tag"]}
+			all, err := s.repo.ListFeeds(ctx, filter, 0)
+			if err != nil {
+				return Response{}, fmt.Errorf("failed to list feeds: %w", err)
+			}
+			feeds = all
+		} else {
+			feed, err := s.repo.ListFeedByName(ctx, req.Args["name"])
+			if err != nil {
+				return Response{}, fmt.Errorf("feed %q not found", req.Args["name"])
+			}
+			feeds = append(feeds, feed)
+		}
+		for _, feed := range feeds {
+			if err := s.agg.Enqueue(ctx, feed); err != nil {
+				return Response{}, err
+			}
+		}
+		return Response{Message: fmt.Sprintf("%d feed(s) queued for fetching", len(feeds))}, nil
+
+	case CmdReload:
+		if err := s.share.Sync(ctx); err != nil {
+			return Response{}, err
+		}
+		return Response{Message: "Settings reloaded from db"}, nil
+
+	default:
+		return Response{}, fmt.Errorf("unknown command: %s", req.Command)
+	}
+}
+
+func (s *Server) status(ctx context.Context) *Status {
+	stats := s.agg.Stats(ctx)
+	interval, _ := utils.ParseDurationToInterval(stats.Interval)
+	return &Status{
+		PID:             os.Getpid(),
+		StartedAt:       s.startedAt,
+		Interval:        interval,
+		Workers:         stats.Workers,
+		BusyWorkers:     stats.BusyWorkers,
+		QueueDepth:      stats.QueueDepth,
+		Paused:          stats.Paused,
+		PausedUntil:     stats.PausedUntil,
+		LastTick:        stats.LastTick,
+		FetchedLastHour: stats.FetchedLastHour,
+		FailedLastHour:  stats.FailedLastHour,
+	}
+}
@@ -0,0 +1,109 @@
+package control
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSocketIsPrivate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "rsshub.sock")
+	s := NewServer(nil, nil, nil, path)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket mode = %v, want 0600", perm)
+	}
+	dir, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := dir.Mode().Perm(); perm != 0o700 {
+		t.Errorf("socket directory mode = %v, want 0700", perm)
+	}
+}
+
+func TestSecondServerRefusesLiveSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rsshub.sock")
+	first := NewServer(nil, nil, nil, path)
+	if err := first.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer first.Stop()
+
+	second := NewServer(nil, nil, nil, path)
+	err := second.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Fatalf("second Start() error = %v, want the socket to be in use", err)
+	}
+	// The failed server must not take the socket of the live one away
+	second.Stop()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("first server no longer answers: %v", err)
+	}
+	conn.Close()
+}
+
+func TestStaleSocketIsReplaced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rsshub.sock")
+	// A socket file nobody listens on, as left by a crashed daemon
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	s := NewServer(nil, nil, nil, path)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() over a stale socket: %v", err)
+	}
+	s.Stop()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket left behind after Stop: %v", err)
+	}
+}
+
+func TestStopKeepsSocketOfAnotherServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rsshub.sock")
+	first := NewServer(nil, nil, nil, path)
+	if err := first.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first.listener.Close()
+
+	// The first server hangs without answering and a new one takes the path over
+	second := NewServer(nil, nil, nil, path)
+	if err := second.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer second.Stop()
+
+	first.Stop()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Stop of the old server removed the socket of the new one: %v", err)
+	}
+}
+
+func TestSocketDirWritableByOthers(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(nil, nil, nil, filepath.Join(dir, "rsshub.sock"))
+	if err := s.Start(context.Background()); err == nil {
+		s.Stop()
+		t.Fatal("Start() succeeded in a directory anyone can write to")
+	}
+}
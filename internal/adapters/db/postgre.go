@@ -1,14 +1,18 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"RSSHub/internal/domain"
+	"RSSHub/internal/domain/utils"
+	"RSSHub/pkg/config"
 	"RSSHub/pkg/logger"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 var _ domain.Repository = (*PostgresRepository)(nil)
@@ -17,16 +21,37 @@ type PostgresRepository struct {
 	db *sql.DB
 }
 
-// NewPostgresRepository creates a new Postgres repo
-func NewPostgresRepository() (*PostgresRepository, error) {
-	connStr := "host=db port=5432 user=postgres password=changeme dbname=rsshub sslmode=disable"
-	db, err := sql.Open("postgres", connStr)
+// NewPostgresRepository opens the connection pool described by cfg,
+// retrying the first connection with exponential backoff while the db is starting up
+func NewPostgresRepository(ctx context.Context, cfg config.DBConfig) (*PostgresRepository, error) {
+	db, err := sql.Open("postgres", cfg.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open db: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping db: %w", err)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	backoff := cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			break
+		}
+		if attempt >= cfg.ConnectRetries {
+			db.Close()
+			return nil, fmt.Errorf("failed to ping db after %d attempt(s): %w", attempt+1, err)
+		}
+
+		logger.Warn("db is not reachable yet, retrying", "error", err, "retry_in", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			db.Close()
+			return nil, fmt.Errorf("failed to ping db: %w", ctx.Err())
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
 	}
 
 	logger.Debug("Succefully connected to Database!")
@@ -34,6 +59,9 @@ func NewPostgresRepository() (*PostgresRepository, error) {
 	return &PostgresRepository{db: db}, nil
 }
 
+// maxRetryBackoff caps the wait between connection attempts
+const maxRetryBackoff = 10 * time.Second
+
 // Close DB connection
 func (r *PostgresRepository) Close() error {
 	return r.db.Close()
@@ -41,47 +69,108 @@ func (r *PostgresRepository) Close() error {
 
 // -------------------------------------------------------------Feeds--------------------------------------------------------------------
 
-func (r *PostgresRepository) AddFeed(feed domain.Feed) error {
+// AddFeed stores a new feed with its tags, a feed with the same name is left as it is
+func (r *PostgresRepository) AddFeed(ctx context.Context, feed domain.Feed) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO feeds (name, url, created_at, updated_at)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (name) DO NOTHING;
+		INSERT INTO feeds (
+			name, url, created_at, updated_at, folder, fetch_interval_seconds,
+			title, site_url, description, language, image_url, icon_url, icon_checked_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id;
 	`
-	_, err := r.db.Exec(query, feed.Name, feed.URL, feed.CreatedAt, feed.UpdatedAt)
-	return err
+	m := feed.Meta
+	var iconCheckedAt sql.NullTime
+	if !m.IconCheckedAt.IsZero() {
+		iconCheckedAt = sql.NullTime{Time: m.IconCheckedAt, Valid: true}
+	}
+	var id string
+	err = tx.QueryRowContext(ctx, query,
+		feed.Name, feed.URL, feed.CreatedAt, feed.UpdatedAt, feed.Folder, int64(feed.Interval/time.Second),
+		m.Title, m.SiteURL, m.Description, m.Language, m.ImageURL, m.IconURL, iconCheckedAt,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := insertTags(ctx, tx, id, feed.Tags); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (r *PostgresRepository) ListFeedByName(feedName string) (domain.Feed, error) {
-	feed := domain.Feed{}
-	query := `
-		SELECT id, name, url, created_at, updated_at
-		FROM feeds
-		WHERE name = $1
-	`
-	err := r.db.QueryRow(query, feedName).Scan(&feed.ID, &feed.Name, &feed.URL, &feed.CreatedAt, &feed.UpdatedAt)
-	if err != nil {
+// feedColumns are read by scanFeed
+const feedColumns = `
+	f.id, f.name, f.url, f.created_at, f.updated_at, f.folder, f.fetch_interval_seconds,
+	ARRAY(SELECT t.tag FROM feed_tags t WHERE t.feed_id = f.id ORDER BY t.tag),
+	f.title, f.site_url, f.description, f.language, f.image_url, f.icon_url, f.icon_checked_at
+`
+
+// scanFeed reads the feedColumns and then the extra columns selected after them
+func scanFeed(row interface{ Scan(...any) error }, extra ...any) (domain.Feed, error) {
+	var f domain.Feed
+	var interval int64
+	var iconCheckedAt sql.NullTime
+	m := &f.Meta
+	dest := []any{
+		&f.ID, &f.Name, &f.URL, &f.CreatedAt, &f.UpdatedAt, &f.Folder, &interval, pq.Array(&f.Tags),
+		&m.Title, &m.SiteURL, &m.Description, &m.Language, &m.ImageURL, &m.IconURL, &iconCheckedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
 		return domain.Feed{}, err
 	}
-	return feed, nil
+	f.Interval = time.Duration(interval) * time.Second
+	m.IconCheckedAt = iconCheckedAt.Time
+	return f, nil
 }
 
-func (r *PostgresRepository) ListFeeds(limit int) ([]domain.Feed, error) {
-	var rows *sql.Rows
-	var err error
-	query := `
-		SELECT id, name, url, created_at, updated_at
-		FROM feeds
-		ORDER BY created_at DESC
-	`
+func (r *PostgresRepository) ListFeedByName(ctx context.Context, feedName string) (domain.Feed, error) {
+	query := `SELECT ` + feedColumns + ` FROM feeds f WHERE f.name = $1`
+	return scanFeed(r.db.QueryRowContext(ctx, query, feedName))
+}
+
+func (r *PostgresRepository) ListFeeds(ctx context.Context, filter domain.FeedFilter, limit int) ([]domain.Feed, error) {
 	if limit < 0 {
 		return nil, fmt.Errorf("--num parameter cannot be negative")
-	} else if limit != 0 {
-		query += "LIMIT $1"
-		rows, err = r.db.Query(query, limit)
-	} else {
-		rows, err = r.db.Query(query)
 	}
 
+	// LIMIT NULL means no limit
+	var limitArg sql.NullInt64
+	if limit != 0 {
+		limitArg = sql.NullInt64{Int64: int64(limit), Valid: true}
+	}
+	query := `
+		SELECT ` + feedColumns + `
+		FROM feeds f
+		WHERE ($1::TEXT = '' OR f.folder = $1)
+			AND ($2::TEXT = '' OR EXISTS (SELECT 1 FROM feed_tags t WHERE t.feed_id = f.id AND t.tag = $2))
+		ORDER BY f.created_at DESC
+		LIMIT $3
+	`
+	return r.queryFeeds(ctx, query, filter.Folder, filter.Tag, limitArg)
+}
+
+// ListDueFeeds returns the feeds not fetched since the given moment, least recently fetched first
+func (r *PostgresRepository) ListDueFeeds(ctx context.Context, fetchedBefore time.Time) ([]domain.Feed, error) {
+	query := `
+		SELECT ` + feedColumns + `
+		FROM feeds f
+		WHERE f.updated_at <= $1
+		ORDER BY f.updated_at ASC
+	`
+	return r.queryFeeds(ctx, query, fetchedBefore)
+}
+
+func (r *PostgresRepository) queryFeeds(ctx context.Context, query string, args ...any) ([]domain.Feed, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -89,18 +178,78 @@ func (r *PostgresRepository) ListFeeds(limit int) ([]domain.Feed, error) {
 
 	var feeds []domain.Feed
 	for rows.Next() {
-		var f domain.Feed
-		err := rows.Scan(&f.ID, &f.Name, &f.URL, &f.CreatedAt, &f.UpdatedAt)
+		f, err := scanFeed(rows)
 		if err != nil {
 			return nil, err
 		}
 		feeds = append(feeds, f)
 	}
-	return feeds, nil
+	return feeds, rows.Err()
 }
 
-func (r *PostgresRepository) UpdateFeedTimestamp(feedID string, updatedAt time.Time) error {
-	_, err := r.db.Exec(`
+// TagFeed adds the tags the feed does not carry yet
+func (r *PostgresRepository) TagFeed(ctx context.Context, feedName string, tags []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	feedID, err := feedIDByName(ctx, tx, feedName)
+	if err != nil {
+		return err
+	}
+	if err := insertTags(ctx, tx, feedID, tags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UntagFeed removes the tags from the feed, tags it does not carry are ignored
+func (r *PostgresRepository) UntagFeed(ctx context.Context, feedName string, tags []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	feedID, err := feedIDByName(ctx, tx, feedName)
+	if err != nil {
+		return err
+	}
+	query := `DELETE FROM feed_tags WHERE feed_id = $1 AND tag = ANY($2::TEXT[])`
+	if _, err := tx.ExecContext(ctx, query, feedID, pq.Array(tags)); err != nil {
+		return fmt.Errorf("failed to untag feed: %w", err)
+	}
+	return tx.Commit()
+}
+
+func feedIDByName(ctx context.Context, tx *sql.Tx, feedName string) (string, error) {
+	var feedID string
+	err := tx.QueryRowContext(ctx, `SELECT id FROM feeds WHERE name = $1`, feedName).Scan(&feedID)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("The feed is not present in db!")
+	}
+	return feedID, err
+}
+
+func insertTags(ctx context.Context, tx *sql.Tx, feedID string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	query := `
+		INSERT INTO feed_tags (feed_id, tag)
+		SELECT $1::UUID, tag FROM unnest($2::TEXT[]) AS tag
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, feedID, pq.Array(tags)); err != nil {
+		return fmt.Errorf("failed to tag feed: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UpdateFeedTimestamp(ctx context.Context, feedID string, updatedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
 		UPDATE feeds 
 		SET updated_at = $1 
 		WHERE id = $2
@@ -108,9 +257,61 @@ func (r *PostgresRepository) UpdateFeedTimestamp(feedID string, updatedAt time.T
 	return err
 }
 
-func (r *PostgresRepository) DeleteFeed(name string) error {
+// UpdateFeed replaces the name, URL, folder and fetch interval of the named feed.
+// Its id stays the same, so the articles and their read state are kept
+func (r *PostgresRepository) UpdateFeed(ctx context.Context, name string, feed domain.Feed) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	feedID, err := feedIDByName(ctx, tx, name)
+	if err != nil {
+		return err
+	}
+
+	if feed.Name != name {
+		var taken bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM feeds WHERE name = $1)`, feed.Name).Scan(&taken); err != nil {
+			return err
+		}
+		if taken {
+			return fmt.Errorf("A feed named %q is already present in db!", feed.Name)
+		}
+	}
+
+	query := `
+		UPDATE feeds
+		SET name = $1, url = $2, folder = $3, fetch_interval_seconds = $4
+		WHERE id = $5
+	`
+	if _, err := tx.ExecContext(ctx, query, feed.Name, feed.URL, feed.Folder, int64(feed.Interval/time.Second), feedID); err != nil {
+		return fmt.Errorf("failed to update feed: %w", err)
+	}
+	return tx.Commit()
+}
+
+// UpdateFeedMeta stores what the feed said about itself on the last fetch
+func (r *PostgresRepository) UpdateFeedMeta(ctx context.Context, feedID string, meta domain.FeedMeta) error {
+	var iconCheckedAt sql.NullTime
+	if !meta.IconCheckedAt.IsZero() {
+		iconCheckedAt = sql.NullTime{Time: meta.IconCheckedAt, Valid: true}
+	}
+	query := `
+		UPDATE feeds
+		SET title = $1, site_url = $2, description = $3, language = $4, image_url = $5, icon_url = $6, icon_checked_at = $7
+		WHERE id = $8
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		meta.Title, meta.SiteURL, meta.Description, meta.Language, meta.ImageURL, meta.IconURL, iconCheckedAt, feedID,
+	)
+	return err
+}
+
+func (r *PostgresRepository) DeleteFeed(ctx context.Context, name string) error {
 	query := `DELETE FROM feeds WHERE name = $1`
-	result, err := r.db.Exec(query, name)
+	result, err := r.db.ExecContext(ctx, query, name)
 	if err != nil {
 		logger.Error("Error deleting feed", "error", err)
 		return err
@@ -131,29 +332,203 @@ func (r *PostgresRepository) DeleteFeed(name string) error {
 
 // -------------------------------------------------------------Articles--------------------------------------------------------------------
 
-// AddArticle inserts a new article (ignores duplicates by link)
-func (r *PostgresRepository) AddArticle(article domain.Article) error {
-	query := `
-		INSERT INTO articles (created_at, updated_at, title, link, description, published_at, feed_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		ON CONFLICT (link) DO NOTHING;
+// AddArticle inserts a new article or refreshes the stored one with the same link when its content changed
+func (r *PostgresRepository) AddArticle(ctx context.Context, article domain.Article) (domain.ArticleStatus, error) {
+	// Articles removed by the retention rules stay removed, even when the feed rewrote their link
+	var pruned bool
+	query := `SELECT EXISTS (SELECT 1 FROM pruned_articles WHERE link = $1 OR ($2 <> '' AND guid = $2))`
+	err := r.db.QueryRowContext(ctx, query, article.Link, article.GUID).Scan(&pruned)
+	if err != nil {
+		return domain.ArticleSkipped, err
+	}
+	if pruned {
+		return domain.ArticleSkipped, nil
+	}
+
+	query = `
+		INSERT INTO articles (created_at, updated_at, title, link, guid, description, content, published_at, feed_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (link) DO UPDATE
+		SET title = EXCLUDED.title, description = EXCLUDED.description, content = EXCLUDED.content, updated_at = EXCLUDED.updated_at
+		WHERE articles.title IS DISTINCT FROM EXCLUDED.title
+			OR articles.description IS DISTINCT FROM EXCLUDED.description
+			OR articles.content IS DISTINCT FROM EXCLUDED.content
+		RETURNING (xmax = 0) AS inserted;
 	`
-	_, err := r.db.Exec(query,
+	var inserted bool
+	err = r.db.QueryRowContext(ctx, query,
 		article.CreatedAt,
 		article.UpdatedAt,
 		article.Title,
 		article.Link,
+		article.GUID,
 		article.Description,
+		article.Content,
 		article.PublishedAt,
 		article.FeedID,
-	)
-	return err
+	).Scan(&inserted)
+	if err == sql.ErrNoRows {
+		// The article is already stored unchanged
+		return domain.ArticleSkipped, nil
+	} else if err != nil {
+		return domain.ArticleSkipped, err
+	}
+
+	if inserted {
+		return domain.ArticleNew, nil
+	}
+	return domain.ArticleUpdated, nil
+}
+
+// ingestBatchSize keeps multi-row inserts well below the 65535 parameters limit of Postgres
+const ingestBatchSize = 500
+
+// IngestFeed stores all articles of a fetched feed and updates its timestamp in one transaction.
+// Articles are written with multi-row inserts; duplicates by link are skipped or refreshed like in AddArticle
+func (r *PostgresRepository) IngestFeed(ctx context.Context, feedID string, articles []domain.Article, fetchedAt time.Time) (domain.IngestStats, error) {
+	var stats domain.IngestStats
+
+	// One statement cannot touch the same row twice, so repeated links are dropped first
+	seen := make(map[string]bool, len(articles))
+	unique := make([]domain.Article, 0, len(articles))
+	for _, a := range articles {
+		if seen[a.Link] {
+			stats.Skipped++
+			continue
+		}
+		seen[a.Link] = true
+		unique = append(unique, a)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.IngestStats{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	unique, err = dropPrunedArticles(ctx, tx, unique)
+	if err != nil {
+		return domain.IngestStats{}, err
+	}
+	stats.Skipped += len(seen) - len(unique)
+
+	for start := 0; start < len(unique); start += ingestBatchSize {
+		batch := unique[start:min(start+ingestBatchSize, len(unique))]
+		inserted, updated, err := insertArticleBatch(ctx, tx, feedID, batch)
+		if err != nil {
+			return domain.IngestStats{}, err
+		}
+		stats.New += inserted
+		stats.Updated += updated
+		stats.Skipped += len(batch) - inserted - updated
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE feeds SET updated_at = $1 WHERE id = $2`, fetchedAt, feedID)
+	if err != nil {
+		return domain.IngestStats{}, fmt.Errorf("failed to update feed timestamp: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.IngestStats{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return stats, nil
+}
+
+// dropPrunedArticles leaves out the articles the retention rules already removed,
+// matching them by link or, when the feed gives one, by GUID
+func dropPrunedArticles(ctx context.Context, tx *sql.Tx, articles []domain.Article) ([]domain.Article, error) {
+	links := make([]string, len(articles))
+	guids := make([]string, 0, len(articles))
+	for i, a := range articles {
+		links[i] = a.Link
+		if a.GUID != "" {
+			guids = append(guids, a.GUID)
+		}
+	}
+
+	query := `SELECT link, guid FROM pruned_articles WHERE link = ANY($1) OR guid = ANY($2)`
+	rows, err := tx.QueryContext(ctx, query, pq.Array(links), pq.Array(guids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pruned articles: %w", err)
+	}
+	defer rows.Close()
+
+	prunedLinks := make(map[string]bool)
+	prunedGUIDs := make(map[string]bool)
+	for rows.Next() {
+		var link, guid string
+		if err := rows.Scan(&link, &guid); err != nil {
+			return nil, err
+		}
+		prunedLinks[link] = true
+		if guid != "" {
+			prunedGUIDs[guid] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(prunedLinks) == 0 {
+		return articles, nil
+	}
+
+	kept := make([]domain.Article, 0, len(articles))
+	for _, a := range articles {
+		if !prunedLinks[a.Link] && (a.GUID == "" || !prunedGUIDs[a.GUID]) {
+			kept = append(kept, a)
+		}
+	}
+	return kept, nil
+}
+
+// insertArticleBatch writes the batch with a single statement and counts inserted and updated rows
+func insertArticleBatch(ctx context.Context, tx *sql.Tx, feedID string, batch []domain.Article) (int, int, error) {
+	const columns = 9
+	var sb strings.Builder
+	sb.WriteString(`INSERT INTO articles (created_at, updated_at, title, link, guid, description, content, published_at, feed_id) VALUES `)
+
+	args := make([]any, 0, len(batch)*columns)
+	for i, a := range batch {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		n := i * columns
+		fmt.Fprintf(&sb, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9)
+		args = append(args, a.CreatedAt, a.UpdatedAt, a.Title, a.Link, a.GUID, a.Description, a.Content, a.PublishedAt, feedID)
+	}
+	sb.WriteString(`
+		ON CONFLICT (link) DO UPDATE
+		SET title = EXCLUDED.title, description = EXCLUDED.description, content = EXCLUDED.content, updated_at = EXCLUDED.updated_at
+		WHERE articles.title IS DISTINCT FROM EXCLUDED.title
+			OR articles.description IS DISTINCT FROM EXCLUDED.description
+			OR articles.content IS DISTINCT FROM EXCLUDED.content
+		RETURNING (xmax = 0) AS inserted;`)
+
+	rows, err := tx.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to insert articles: %w", err)
+	}
+	defer rows.Close()
+
+	inserted, updated := 0, 0
+	for rows.Next() {
+		var isNew bool
+		if err := rows.Scan(&isNew); err != nil {
+			return 0, 0, err
+		}
+		if isNew {
+			inserted++
+		} else {
+			updated++
+		}
+	}
+	return inserted, updated, rows.Err()
 }
 
 // ListArticles returns the N latest articles for a feed
-func (r *PostgresRepository) ListArticles(feedName string, num int) ([]domain.Article, error) {
+func (r *PostgresRepository) ListArticles(ctx context.Context, feedName string, num int) ([]domain.Article, error) {
 	query := `
-		SELECT a.id, a.created_at, a.updated_at, a.title, a.link, a.description, a.published_at, a.feed_id
+		SELECT a.id, a.created_at, a.updated_at, a.title, a.link, a.guid, a.description, a.content, a.published_at, a.feed_id, a.read, a.starred
 		FROM articles a
 		JOIN feeds f ON a.feed_id = f.id
 		WHERE f.name = $1
@@ -161,7 +536,7 @@ func (r *PostgresRepository) ListArticles(feedName string, num int) ([]domain.Ar
 		LIMIT $2;
 	`
 
-	rows, err := r.db.Query(query, feedName, num)
+	rows, err := r.db.QueryContext(ctx, query, feedName, num)
 	if err != nil {
 		return nil, err
 	}
@@ -170,7 +545,7 @@ func (r *PostgresRepository) ListArticles(feedName string, num int) ([]domain.Ar
 	var articles []domain.Article
 	for rows.Next() {
 		var a domain.Article
-		err := rows.Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt, &a.Title, &a.Link, &a.Description, &a.PublishedAt, &a.FeedID)
+		err := rows.Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt, &a.Title, &a.Link, &a.GUID, &a.Description, &a.Content, &a.PublishedAt, &a.FeedID, &a.Read, &a.Starred)
 		if err != nil {
 			return nil, err
 		}
@@ -179,16 +554,18 @@ func (r *PostgresRepository) ListArticles(feedName string, num int) ([]domain.Ar
 	return articles, nil
 }
 
-// ListArticlesByFeed returns the N most recent articles for a feed
-func (r *PostgresRepository) ListArticlesByFeed(feedID string, limit int) ([]domain.Article, error) {
+// ListArticlesByFeed returns the N most recent articles for a feed that pass the filter
+func (r *PostgresRepository) ListArticlesByFeed(ctx context.Context, feedID string, filter domain.ArticleFilter, limit int) ([]domain.Article, error) {
 	query := `
-		SELECT id, feed_id, title, link, description, published_at, created_at, updated_at
+		SELECT id, feed_id, title, link, guid, description, content, published_at, created_at, updated_at, read, starred
 		FROM articles
 		WHERE feed_id = $1
+			AND (NOT $2 OR NOT read)
+			AND (NOT $3 OR starred)
 		ORDER BY published_at DESC
-		LIMIT $2;`
+		LIMIT $4;`
 
-	rows, err := r.db.Query(query, feedID, limit)
+	rows, err := r.db.QueryContext(ctx, query, feedID, filter.Unread, filter.Starred, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -198,8 +575,8 @@ func (r *PostgresRepository) ListArticlesByFeed(feedID string, limit int) ([]dom
 	for rows.Next() {
 		var a domain.Article
 		err := rows.Scan(
-			&a.ID, &a.FeedID, &a.Title, &a.Link,
-			&a.Description, &a.PublishedAt, &a.CreatedAt, &a.UpdatedAt,
+			&a.ID, &a.FeedID, &a.Title, &a.Link, &a.GUID,
+			&a.Description, &a.Content, &a.PublishedAt, &a.CreatedAt, &a.UpdatedAt, &a.Read, &a.Starred,
 		)
 		if err != nil {
 			return nil, err
@@ -210,47 +587,343 @@ func (r *PostgresRepository) ListArticlesByFeed(feedID string, limit int) ([]dom
 	return articles, nil
 }
 
+func (r *PostgresRepository) SetArticleRead(ctx context.Context, id string, read bool) error {
+	return r.setArticleFlag(ctx, `UPDATE articles SET read = $1 WHERE id = $2`, read, id)
+}
+
+func (r *PostgresRepository) SetArticleStarred(ctx context.Context, id string, starred bool) error {
+	return r.setArticleFlag(ctx, `UPDATE articles SET starred = $1 WHERE id = $2`, starred, id)
+}
+
+func (r *PostgresRepository) setArticleFlag(ctx context.Context, query string, value bool, id string) error {
+	// Ids come from the user, a malformed one would otherwise fail the uuid cast
+	if !utils.IsUUID(id) {
+		return fmt.Errorf("The article is not present in db!")
+	}
+
+	result, err := r.db.ExecContext(ctx, query, value, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("The article is not present in db!")
+	}
+	return nil
+}
+
+// MarkFeedRead marks every article of a feed as read and returns how many were unread
+func (r *PostgresRepository) MarkFeedRead(ctx context.Context, feedID string) (int, error) {
+	result, err := r.db.ExecContext(ctx, `UPDATE articles SET read = TRUE WHERE feed_id = $1 AND NOT read`, feedID)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return int(rowsAffected), err
+}
+
+// CountUnreadArticles returns the number of unread articles by feed id, feeds without any are left out
+func (r *PostgresRepository) CountUnreadArticles(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT feed_id, COUNT(*) FROM articles WHERE NOT read GROUP BY feed_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var feedID string
+		var count int
+		if err := rows.Scan(&feedID, &count); err != nil {
+			return nil, err
+		}
+		counts[feedID] = count
+	}
+	return counts, rows.Err()
+}
+
+// Timeline merges the articles of several feeds, newest first, one page at a time.
+// Pages are cut with a keyset on (published_at, id) so that new articles do not shift them
+func (r *PostgresRepository) Timeline(ctx context.Context, q domain.TimelineQuery) ([]domain.TimelineEntry, error) {
+	var since, until, afterTime sql.NullTime
+	var afterID sql.NullString
+	if !q.Since.IsZero() {
+		since = sql.NullTime{Time: q.Since, Valid: true}
+	}
+	if !q.Until.IsZero() {
+		until = sql.NullTime{Time: q.Until, Valid: true}
+	}
+	if q.After != nil {
+		if !utils.IsUUID(q.After.ID) {
+			return nil, domain.ErrInvalidCursor
+		}
+		afterTime = sql.NullTime{Time: q.After.PublishedAt, Valid: true}
+		afterID = sql.NullString{String: q.After.ID, Valid: true}
+	}
+
+	// A nil slice would be sent as NULL instead of an empty array
+	feedNames := q.FeedNames
+	if feedNames == nil {
+		feedNames = []string{}
+	}
+
+	query := `
+		SELECT a.id, a.created_at, a.updated_at, a.title, a.link, a.guid, a.description, a.content, a.published_at, a.feed_id, a.read, a.starred,
+			f.name
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		WHERE (cardinality($1::TEXT[]) = 0 OR f.name = ANY($1))
+			AND ($9::TEXT = '' OR f.folder = $9)
+			AND ($10::TEXT = '' OR EXISTS (SELECT 1 FROM feed_tags t WHERE t.feed_id = f.id AND t.tag = $10))
+			AND ($2::TIMESTAMP IS NULL OR a.published_at >= $2)
+			AND ($3::TIMESTAMP IS NULL OR a.published_at < $3)
+			AND (NOT $4 OR NOT a.read)
+			AND (NOT $5 OR a.starred)
+			AND ($6::TIMESTAMP IS NULL OR (a.published_at, a.id) < ($6, $7::UUID))
+		ORDER BY a.published_at DESC, a.id DESC
+		LIMIT $8;
+	`
+	rows, err := r.db.QueryContext(ctx, query,
+		pq.Array(feedNames), since, until, q.Filter.Unread, q.Filter.Starred, afterTime, afterID, q.Limit,
+		q.Feeds.Folder, q.Feeds.Tag,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []domain.TimelineEntry
+	for rows.Next() {
+		var e domain.TimelineEntry
+		a := &e.Article
+		err := rows.Scan(
+			&a.ID, &a.CreatedAt, &a.UpdatedAt, &a.Title, &a.Link, &a.GUID, &a.Description, &a.Content, &a.PublishedAt, &a.FeedID, &a.Read, &a.Starred,
+			&e.FeedName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// SearchArticles ranks the articles matching the query by ts_rank over the weighted search vector.
+// The snippet is taken from the content, or the description when the feed has no full text
+func (r *PostgresRepository) SearchArticles(ctx context.Context, q domain.SearchQuery) ([]domain.SearchResult, error) {
+	var since sql.NullTime
+	if !q.Since.IsZero() {
+		since = sql.NullTime{Time: q.Since, Valid: true}
+	}
+
+	query := `
+		SELECT a.id, a.created_at, a.updated_at, a.title, a.link, a.guid, a.description, a.content, a.published_at, a.feed_id, a.read, a.starred,
+			f.name,
+			ts_rank(a.search_vector, q.query) AS rank,
+			ts_headline('english',
+				regexp_replace(coalesce(nullif(a.content, ''), a.description, a.title), '<[^>]*>', ' ', 'g'),
+				q.query, $5)
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id,
+			websearch_to_tsquery('english', $1) AS q(query)
+		WHERE a.search_vector @@ q.query
+			AND ($2 = '' OR f.name = $2)
+			AND ($3::TIMESTAMP IS NULL OR a.published_at >= $3)
+		ORDER BY rank DESC, a.published_at DESC
+		LIMIT $4 OFFSET $6;
+	`
+	headline := fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=30, MinWords=10, MaxFragments=2",
+		domain.HighlightStart, domain.HighlightStop)
+
+	rows, err := r.db.QueryContext(ctx, query, q.Text, q.FeedName, since, q.Limit, headline, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.SearchResult
+	for rows.Next() {
+		var res domain.SearchResult
+		a := &res.Article
+		err := rows.Scan(
+			&a.ID, &a.CreatedAt, &a.UpdatedAt, &a.Title, &a.Link, &a.GUID, &a.Description, &a.Content, &a.PublishedAt, &a.FeedID, &a.Read, &a.Starred,
+			&res.FeedName, &res.Rank, &res.Snippet,
+		)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// -------------------------------------------------------------Fetch jobs--------------------------------------------------------------------
+
+// EnqueueFetchJob queues a feed for fetching. If the feed is already queued its priority is raised when needed
+func (r *PostgresRepository) EnqueueFetchJob(ctx context.Context, feedID string, priority int) error {
+	query := `
+		INSERT INTO fetch_jobs (feed_id, priority)
+		VALUES ($1, $2)
+		ON CONFLICT (feed_id) WHERE state IN ('queued', 'running')
+		DO UPDATE SET priority = GREATEST(fetch_jobs.priority, EXCLUDED.priority), updated_at = NOW();
+	`
+	_, err := r.db.ExecContext(ctx, query, feedID, priority)
+	return err
+}
+
+// ClaimFetchJob leases the most urgent queued job, or a running job whose lease has expired
+func (r *PostgresRepository) ClaimFetchJob(ctx context.Context, lease time.Duration) (domain.FetchJob, error) {
+	// Jobs that ran out of their lease on the last allowed attempt are given up
+	query := `
+		UPDATE fetch_jobs
+		SET state = 'failed', leased_until = NULL, last_error = $2, updated_at = NOW()
+		WHERE state = 'running' AND leased_until < NOW() AND attempts >= $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, domain.MaxJobAttempts, domain.AbandonedJobError); err != nil {
+		return domain.FetchJob{}, fmt.Errorf("failed to give up abandoned jobs: %w", err)
+	}
+
+	query = `
+		WITH picked AS (
+			UPDATE fetch_jobs
+			SET state = 'running', attempts = attempts + 1, leased_until = NOW() + make_interval(secs => $1), updated_at = NOW()
+			WHERE id = (
+				SELECT id FROM fetch_jobs
+				WHERE state = 'queued' OR (state = 'running' AND leased_until < NOW() AND attempts < $2)
+				ORDER BY priority DESC, created_at
+				FOR UPDATE SKIP LOCKED
+				LIMIT 1
+			)
+			RETURNING id, feed_id, state, priority, attempts, leased_until, created_at, updated_at
+		)
+		SELECT ` + feedColumns + `,
+			p.id, p.state, p.priority, p.attempts, p.leased_until, p.created_at, p.updated_at
+		FROM picked p
+		JOIN feeds f ON f.id = p.feed_id;
+	`
+	var job domain.FetchJob
+	var err error
+	job.Feed, err = scanFeed(r.db.QueryRowContext(ctx, query, lease.Seconds(), domain.MaxJobAttempts),
+		&job.ID, &job.State, &job.Priority, &job.Attempts, &job.LeasedUntil, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return domain.FetchJob{}, domain.ErrNoJobs
+	} else if err != nil {
+		return domain.FetchJob{}, err
+	}
+	return job, nil
+}
+
+func (r *PostgresRepository) CompleteFetchJob(ctx context.Context, id int64) error {
+	query := `UPDATE fetch_jobs SET state = 'done', leased_until = NULL, updated_at = NOW() WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *PostgresRepository) FailFetchJob(ctx context.Context, id int64, reason string) error {
+	query := `UPDATE fetch_jobs SET state = 'failed', leased_until = NULL, last_error = $2, updated_at = NOW() WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id, reason)
+	return err
+}
+
+// ReleaseFetchJob puts an unfinished job back in the queue so it is picked up without waiting for its lease
+func (r *PostgresRepository) ReleaseFetchJob(ctx context.Context, id int64) error {
+	query := `UPDATE fetch_jobs SET state = 'queued', leased_until = NULL, updated_at = NOW() WHERE id = $1 AND state = 'running'`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *PostgresRepository) CountQueuedFetchJobs(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*) FROM fetch_jobs WHERE state = 'queued'`
+	var count int
+	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	return count, err
+}
+
+// PurgeFetchJobs deletes done and failed jobs that finished before the given moment
+func (r *PostgresRepository) PurgeFetchJobs(ctx context.Context, finishedBefore time.Time) error {
+	query := `DELETE FROM fetch_jobs WHERE state IN ('done', 'failed') AND updated_at < $1`
+	_, err := r.db.ExecContext(ctx, query, finishedBefore)
+	return err
+}
+
 // -------------------------------------------------------------Share--------------------------------------------------------------------
 
-func (r *PostgresRepository) FetchCliInterval() (string, error) {
-	query := `SELECT interval FROM share`
+func (r *PostgresRepository) FetchCliInterval(ctx context.Context) (string, error) {
+	query := `SELECT interval FROM share WHERE id = 1 AND interval <> '' AND workers_num > 0`
 	var interval string
-	err := r.db.QueryRow(query).Scan(&interval)
+	err := r.db.QueryRowContext(ctx, query).Scan(&interval)
 	if err == sql.ErrNoRows {
 		return "", err
 	}
 	return interval, nil
 }
 
-func (r *PostgresRepository) SetInterval(interval string) error {
+func (r *PostgresRepository) SetInterval(ctx context.Context, interval string) error {
 	query := `UPDATE share SET interval = $1 WHERE id = 1`
-	_, err := r.db.Exec(query, interval)
+	_, err := r.db.ExecContext(ctx, query, interval)
 	return err
 }
 
-func (r *PostgresRepository) SetDefaultCliIntervalAndWorkersNum(interval string, workersNum int) error {
+func (r *PostgresRepository) SetDefaultCliIntervalAndWorkersNum(ctx context.Context, interval string, workersNum int) error {
 	query := `
 		INSERT INTO share (id, interval, workers_num)
 		VALUES (1, $1, $2)
 		ON CONFLICT (id)
 		DO UPDATE SET interval = EXCLUDED.interval, workers_num = EXCLUDED.workers_num;
 	`
-	_, err := r.db.Exec(query, interval, workersNum)
+	_, err := r.db.ExecContext(ctx, query, interval, workersNum)
 	return err
 }
 
-func (r *PostgresRepository) SetWorkers(workersNum int) error {
+func (r *PostgresRepository) SetWorkers(ctx context.Context, workersNum int) error {
 	query := `UPDATE share SET workers_num = $1 WHERE id = 1`
-	_, err := r.db.Exec(query, workersNum)
+	_, err := r.db.ExecContext(ctx, query, workersNum)
 	return err
 }
 
-func (r *PostgresRepository) FetchWorkersNumber() (int, error) {
-	query := `SELECT workers_num FROM share`
+func (r *PostgresRepository) FetchWorkersNumber(ctx context.Context) (int, error) {
+	query := `SELECT workers_num FROM share WHERE id = 1 AND interval <> '' AND workers_num > 0`
 	var workersNum int
-	err := r.db.QueryRow(query).Scan(&workersNum)
+	err := r.db.QueryRowContext(ctx, query).Scan(&workersNum)
 	if err == sql.ErrNoRows {
 		return 0, err
 	}
 	return workersNum, nil
 }
+
+func (r *PostgresRepository) SetPauseState(ctx context.Context, state domain.PauseState) error {
+	var until sql.NullTime
+	if state.Paused && !state.Until.IsZero() {
+		until = sql.NullTime{Time: state.Until, Valid: true}
+	}
+
+	// Pausing works before the first fetch too, the row it creates has no
+	// interval and worker count yet and FetchCliInterval keeps reporting sql.ErrNoRows
+	query := `
+		INSERT INTO share (id, interval, workers_num, paused, paused_until)
+		VALUES (1, '', 0, $1, $2)
+		ON CONFLICT (id)
+		DO UPDATE SET paused = EXCLUDED.paused, paused_until = EXCLUDED.paused_until;
+	`
+	_, err := r.db.ExecContext(ctx, query, state.Paused, until)
+	return err
+}
+
+func (r *PostgresRepository) FetchPauseState(ctx context.Context) (domain.PauseState, error) {
+	query := `SELECT paused, paused_until FROM share WHERE id = 1`
+	var state domain.PauseState
+	var until sql.NullTime
+	err := r.db.QueryRowContext(ctx, query).Scan(&state.Paused, &until)
+	if err != nil {
+		return domain.PauseState{}, err
+	}
+	if until.Valid {
+		state.Until = until.Time
+	}
+	return state, nil
+}
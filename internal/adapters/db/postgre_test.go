@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"RSSHub/internal/adapters/repotest"
+	"RSSHub/internal/domain"
+	"RSSHub/migrations"
+	"RSSHub/pkg/config"
+)
+
+// testDSNEnv names the database the tests run against. It is not DATABASE_URL, which the app
+// itself reads, so that running the tests in a shell set up for the app cannot touch its data
+const testDSNEnv = "RSSHUB_TEST_DATABASE_URL"
+
+// newTestRepository connects to the database of RSSHUB_TEST_DATABASE_URL, migrates it and empties
+// every table. The test is skipped when the variable is not set, and it fails without touching
+// anything when the name of the database does not end in _test
+func newTestRepository(tb testing.TB) *PostgresRepository {
+	tb.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		tb.Skip(testDSNEnv + " is not set")
+	}
+
+	ctx := context.Background()
+	cfg, err := config.GetDBConfig()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	cfg.DSN = dsn
+	repo, err := NewPostgresRepository(ctx, cfg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var name string
+	if err := repo.db.QueryRowContext(ctx, `SELECT current_database()`).Scan(&name); err != nil {
+		repo.Close()
+		tb.Fatal(err)
+	}
+	if !strings.HasSuffix(name, "_test") {
+		repo.Close()
+		tb.Fatalf("refusing to empty database %q: the name of a test database must end in _test", name)
+	}
+
+	allMigrations, err := migrations.Load()
+	if err != nil {
+		repo.Close()
+		tb.Fatal(err)
+	}
+	if _, err := repo.MigrateUp(ctx, allMigrations); err != nil {
+		repo.Close()
+		tb.Fatal(err)
+	}
+
+	query := `
+		DO $$
+		DECLARE t TEXT;
+		BEGIN
+			FOR t IN SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename <> 'schema_migrations' LOOP
+				EXECUTE format('TRUNCATE TABLE %I CASCADE', t);
+			END LOOP;
+		END $$;
+	`
+	if _, err := repo.db.ExecContext(ctx, query); err != nil {
+		repo.Close()
+		tb.Fatal(err)
+	}
+	return repo
+}
+
+func TestPostgresRepository(t *testing.T) {
+	if os.Getenv(testDSNEnv) == "" {
+		t.Skip(testDSNEnv + " is not set")
+	}
+	repotest.TestRepository(t, func(t *testing.T) domain.Repository {
+		return newTestRepository(t)
+	})
+}
+
+func BenchmarkIngestFeed(b *testing.B) {
+	repo := newTestRepository(b)
+	defer repo.Close()
+	repotest.BenchmarkIngestFeed(b, repo)
+}
+
+func BenchmarkAddArticleLoop(b *testing.B) {
+	repo := newTestRepository(b)
+	defer repo.Close()
+	repotest.BenchmarkAddArticleLoop(b, repo)
+}
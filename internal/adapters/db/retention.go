@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"RSSHub/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+// SetFeedRetention stores the feed's own retention rules, a zero policy falls back to the global rules
+func (r *PostgresRepository) SetFeedRetention(ctx context.Context, feedName string, policy domain.RetentionPolicy) error {
+	query := `
+		UPDATE feeds
+		SET retention_max_articles = $1, retention_max_age_seconds = $2
+		WHERE name = $3
+	`
+	result, err := r.db.ExecContext(ctx, query, policy.MaxArticles, int64(policy.MaxAge/time.Second), feedName)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("The feed is not present in db!")
+	}
+	return nil
+}
+
+// ListFeedRetention returns the rules of the feeds that have their own, by feed id
+func (r *PostgresRepository) ListFeedRetention(ctx context.Context) (map[string]domain.RetentionPolicy, error) {
+	query := `
+		SELECT id, retention_max_articles, retention_max_age_seconds
+		FROM feeds
+		WHERE retention_max_articles > 0 OR retention_max_age_seconds > 0
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make(map[string]domain.RetentionPolicy)
+	for rows.Next() {
+		var id string
+		var policy domain.RetentionPolicy
+		var maxAge int64
+		if err := rows.Scan(&id, &policy.MaxArticles, &maxAge); err != nil {
+			return nil, err
+		}
+		policy.MaxAge = time.Duration(maxAge) * time.Second
+		policies[id] = policy
+	}
+	return policies, rows.Err()
+}
+
+// PruneArticles deletes the unstarred articles of a feed beyond its policy and remembers their links and GUIDs,
+// so that they are not stored again by the next fetch. With dryRun it only returns what would be deleted
+func (r *PostgresRepository) PruneArticles(ctx context.Context, feedID string, policy domain.RetentionPolicy, now time.Time, dryRun bool) ([]domain.Article, error) {
+	if policy.IsZero() {
+		return nil, nil
+	}
+
+	var cutoff sql.NullTime
+	if policy.MaxAge > 0 {
+		cutoff = sql.NullTime{Time: now.Add(-policy.MaxAge), Valid: true}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, feed_id, title, link, guid, published_at, created_at, updated_at
+		FROM (
+			SELECT a.*, ROW_NUMBER() OVER (ORDER BY published_at DESC, created_at DESC) AS position
+			FROM articles a
+			WHERE feed_id = $1
+		) ranked
+		WHERE NOT starred
+			AND (($2 > 0 AND position > $2) OR ($3::TIMESTAMP IS NOT NULL AND published_at < $3))
+		ORDER BY published_at DESC;
+	`
+	rows, err := tx.QueryContext(ctx, query, feedID, policy.MaxArticles, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select articles to prune: %w", err)
+	}
+	defer rows.Close()
+
+	var articles []domain.Article
+	var ids, links, guids []string
+	for rows.Next() {
+		var a domain.Article
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.Title, &a.Link, &a.GUID, &a.PublishedAt, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+		ids = append(ids, a.ID)
+		links = append(links, a.Link)
+		guids = append(guids, a.GUID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if dryRun || len(articles) == 0 {
+		return articles, nil
+	}
+
+	query = `
+		INSERT INTO pruned_articles (link, guid, feed_id, pruned_at)
+		SELECT p.link, p.guid, $3::UUID, $4::TIMESTAMP FROM unnest($1::TEXT[], $2::TEXT[]) AS p(link, guid)
+		ON CONFLICT (link) DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, pq.Array(links), pq.Array(guids), feedID, now); err != nil {
+		return nil, fmt.Errorf("failed to remember pruned articles: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM articles WHERE id = ANY($1::UUID[])`, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("failed to delete articles: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return articles, nil
+}
+
+// ListPrunedArticles returns what is remembered of the pruned articles of a feed, oldest first
+func (r *PostgresRepository) ListPrunedArticles(ctx context.Context, feedID string) ([]domain.PrunedArticle, error) {
+	query := `SELECT link, guid, pruned_at FROM pruned_articles WHERE feed_id = $1 ORDER BY pruned_at, link`
+	rows, err := r.db.QueryContext(ctx, query, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pruned []domain.PrunedArticle
+	for rows.Next() {
+		var p domain.PrunedArticle
+		if err := rows.Scan(&p.Link, &p.GUID, &p.PrunedAt); err != nil {
+			return nil, err
+		}
+		pruned = append(pruned, p)
+	}
+	return pruned, rows.Err()
+}
+
+// AddPrunedArticles remembers articles pruned elsewhere, e.g. in a restored backup, so that fetching
+// the feed does not store them again. Links that are already remembered are left as they are
+func (r *PostgresRepository) AddPrunedArticles(ctx context.Context, feedID string, pruned []domain.PrunedArticle) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO pruned_articles (link, guid, feed_id, pruned_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (link) DO NOTHING
+	`
+	for _, p := range pruned {
+		if _, err := tx.ExecContext(ctx, query, p.Link, p.GUID, feedID, p.PrunedAt); err != nil {
+			return fmt.Errorf("failed to remember pruned article: %w", err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,929 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"RSSHub/internal/domain"
+	"RSSHub/internal/domain/utils"
+)
+
+var _ domain.Storage = (*MemoryRepository)(nil)
+
+// MemoryRepository keeps everything in process memory, for tests and dry runs.
+// It follows the semantics of db.PostgresRepository: feed names and article links are unique,
+// lookups of missing rows return sql.ErrNoRows and lists come back in the same order
+type MemoryRepository struct {
+	mu        sync.Mutex
+	feeds     map[string]domain.Feed            // by id
+	articles  map[string]domain.Article         // by link
+	retention map[string]domain.RetentionPolicy // by feed id
+	pruned    map[string]prunedRow              // by link
+	prunedIDs map[string]string                 // feed id by GUID of a pruned article
+	jobs      map[int64]domain.FetchJob         // feed is resolved on read, only Feed.ID is kept
+	nextJobID int64
+	share     *shareRow
+	schema    int64
+}
+
+type prunedRow struct {
+	feedID string
+	domain.PrunedArticle
+}
+
+type shareRow struct {
+	interval   string
+	workersNum int
+	pause      domain.PauseState
+}
+
+// initialized tells whether fetch has stored its settings, a pause alone leaves them empty
+func (s *shareRow) initialized() bool {
+	return s != nil && s.interval != "" && s.workersNum > 0
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		feeds:     make(map[string]domain.Feed),
+		articles:  make(map[string]domain.Article),
+		retention: make(map[string]domain.RetentionPolicy),
+		pruned:    make(map[string]prunedRow),
+		prunedIDs: make(map[string]string),
+		jobs:      make(map[int64]domain.FetchJob),
+	}
+}
+
+// Close is a no-op, the data lives as long as the repository value
+func (r *MemoryRepository) Close() error {
+	return nil
+}
+
+// -------------------------------------------------------------Feeds--------------------------------------------------------------------
+
+func (r *MemoryRepository) AddFeed(ctx context.Context, feed domain.Feed) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.feedByName(feed.Name); ok {
+		return nil
+	}
+	feed.ID = utils.NewUUID()
+	feed.Tags = mergeTags(nil, feed.Tags)
+	r.feeds[feed.ID] = feed
+	return nil
+}
+
+func (r *MemoryRepository) ListFeedByName(ctx context.Context, feedName string) (domain.Feed, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Feed{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feed, ok := r.feedByName(feedName)
+	if !ok {
+		return domain.Feed{}, sql.ErrNoRows
+	}
+	return feed, nil
+}
+
+func (r *MemoryRepository) ListFeeds(ctx context.Context, filter domain.FeedFilter, limit int) ([]domain.Feed, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("--num parameter cannot be negative")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feeds := r.filterFeeds(filter.Match)
+	sort.SliceStable(feeds, func(i, j int) bool { return feeds[i].CreatedAt.After(feeds[j].CreatedAt) })
+	if limit > 0 && len(feeds) > limit {
+		feeds = feeds[:limit]
+	}
+	return feeds, nil
+}
+
+// ListDueFeeds returns the feeds not fetched since the given moment, least recently fetched first
+func (r *MemoryRepository) ListDueFeeds(ctx context.Context, fetchedBefore time.Time) ([]domain.Feed, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feeds := r.filterFeeds(func(f domain.Feed) bool { return !f.UpdatedAt.After(fetchedBefore) })
+	sort.SliceStable(feeds, func(i, j int) bool { return feeds[i].UpdatedAt.Before(feeds[j].UpdatedAt) })
+	return feeds, nil
+}
+
+func (r *MemoryRepository) UpdateFeedTimestamp(ctx context.Context, feedID string, updatedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if feed, ok := r.feeds[feedID]; ok {
+		feed.UpdatedAt = updatedAt
+		r.feeds[feedID] = feed
+	}
+	return nil
+}
+
+// TagFeed adds the tags the feed does not carry yet
+func (r *MemoryRepository) TagFeed(ctx context.Context, feedName string, tags []string) error {
+	return r.updateTags(ctx, feedName, func(current []string) []string {
+		return mergeTags(current, tags)
+	})
+}
+
+// UntagFeed removes the tags from the feed, tags it does not carry are ignored
+func (r *MemoryRepository) UntagFeed(ctx context.Context, feedName string, tags []string) error {
+	return r.updateTags(ctx, feedName, func(current []string) []string {
+		var kept []string
+		for _, tag := range current {
+			if !slices.Contains(tags, tag) {
+				kept = append(kept, tag)
+			}
+		}
+		return kept
+	})
+}
+
+// updateTags replaces the tags of a feed with a new slice, stored slices are shared with callers
+func (r *MemoryRepository) updateTags(ctx context.Context, feedName string, update func([]string) []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feed, ok := r.feedByName(feedName)
+	if !ok {
+		return fmt.Errorf("The feed is not present in db!")
+	}
+	feed.Tags = update(feed.Tags)
+	r.feeds[feed.ID] = feed
+	return nil
+}
+
+// mergeTags returns a new sorted slice with the tags of both
+func mergeTags(current, tags []string) []string {
+	merged := slices.Clone(current)
+	for _, tag := range tags {
+		if !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// UpdateFeed replaces the name, URL, folder and fetch interval of the named feed.
+// Its id stays the same, so the articles and their read state are kept
+func (r *MemoryRepository) UpdateFeed(ctx context.Context, name string, feed domain.Feed) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.feedByName(name)
+	if !ok {
+		return fmt.Errorf("The feed is not present in db!")
+	}
+	if _, taken := r.feedByName(feed.Name); taken && feed.Name != name {
+		return fmt.Errorf("A feed named %q is already present in db!", feed.Name)
+	}
+
+	stored.Name = feed.Name
+	stored.URL = feed.URL
+	stored.Folder = feed.Folder
+	stored.Interval = feed.Interval
+	r.feeds[stored.ID] = stored
+	return nil
+}
+
+// UpdateFeedMeta stores what the feed said about itself on the last fetch
+func (r *MemoryRepository) UpdateFeedMeta(ctx context.Context, feedID string, meta domain.FeedMeta) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if feed, ok := r.feeds[feedID]; ok {
+		feed.Meta = meta
+		r.feeds[feedID] = feed
+	}
+	return nil
+}
+
+// DeleteFeed removes the feed together with its articles and fetch jobs, like ON DELETE CASCADE
+func (r *MemoryRepository) DeleteFeed(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feed, ok := r.feedByName(name)
+	if !ok {
+		return fmt.Errorf("The feed is not present in db!")
+	}
+	delete(r.feeds, feed.ID)
+	for link, a := range r.articles {
+		if a.FeedID == feed.ID {
+			delete(r.articles, link)
+		}
+	}
+	for id, job := range r.jobs {
+		if job.Feed.ID == feed.ID {
+			delete(r.jobs, id)
+		}
+	}
+	for link, p := range r.pruned {
+		if p.feedID == feed.ID {
+			delete(r.pruned, link)
+		}
+	}
+	for guid, feedID := range r.prunedIDs {
+		if feedID == feed.ID {
+			delete(r.prunedIDs, guid)
+		}
+	}
+	delete(r.retention, feed.ID)
+	return nil
+}
+
+// feedByName must be called with the lock held
+func (r *MemoryRepository) feedByName(name string) (domain.Feed, bool) {
+	for _, f := range r.feeds {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return domain.Feed{}, false
+}
+
+// filterFeeds must be called with the lock held
+func (r *MemoryRepository) filterFeeds(keep func(domain.Feed) bool) []domain.Feed {
+	var feeds []domain.Feed
+	for _, f := range r.feeds {
+		if keep(f) {
+			feeds = append(feeds, f)
+		}
+	}
+	return feeds
+}
+
+// -------------------------------------------------------------Articles--------------------------------------------------------------------
+
+// AddArticle inserts a new article or refreshes the stored one with the same link when its content changed
+func (r *MemoryRepository) AddArticle(ctx context.Context, article domain.Article) (domain.ArticleStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ArticleSkipped, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.feeds[article.FeedID]; !ok {
+		return domain.ArticleSkipped, fmt.Errorf("feed %s does not exist", article.FeedID)
+	}
+	return r.upsertArticle(article.FeedID, article), nil
+}
+
+// IngestFeed stores all articles of a fetched feed and updates its timestamp at once
+func (r *MemoryRepository) IngestFeed(ctx context.Context, feedID string, articles []domain.Article, fetchedAt time.Time) (domain.IngestStats, error) {
+	var stats domain.IngestStats
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feed, ok := r.feeds[feedID]
+	if !ok {
+		return stats, fmt.Errorf("feed %s does not exist", feedID)
+	}
+
+	// Repeated links within one fetch count as skipped, as in the Postgres batch insert
+	seen := make(map[string]bool, len(articles))
+	for _, a := range articles {
+		if seen[a.Link] {
+			stats.Skipped++
+			continue
+		}
+		seen[a.Link] = true
+		stats.Add(r.upsertArticle(feedID, a))
+	}
+
+	feed.UpdatedAt = fetchedAt
+	r.feeds[feedID] = feed
+	return stats, nil
+}
+
+// upsertArticle must be called with the lock held. Articles removed by the retention rules stay removed,
+// even when the feed rewrote their link
+func (r *MemoryRepository) upsertArticle(feedID string, a domain.Article) domain.ArticleStatus {
+	if _, pruned := r.pruned[a.Link]; pruned {
+		return domain.ArticleSkipped
+	}
+	if _, pruned := r.prunedIDs[a.GUID]; pruned && a.GUID != "" {
+		return domain.ArticleSkipped
+	}
+
+	stored, ok := r.articles[a.Link]
+	if !ok {
+		a.ID = utils.NewUUID()
+		a.FeedID = feedID
+		a.Read = false
+		a.Starred = false
+		r.articles[a.Link] = a
+		return domain.ArticleNew
+	}
+
+	if stored.Title == a.Title && stored.Description == a.Description && stored.Content == a.Content {
+		return domain.ArticleSkipped
+	}
+	stored.Title = a.Title
+	stored.Description = a.Description
+	stored.Content = a.Content
+	stored.UpdatedAt = a.UpdatedAt
+	r.articles[a.Link] = stored
+	return domain.ArticleUpdated
+}
+
+// ListArticles returns the N latest articles for a feed
+func (r *MemoryRepository) ListArticles(ctx context.Context, feedName string, num int) ([]domain.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feed, ok := r.feedByName(feedName)
+	if !ok {
+		return nil, nil
+	}
+	return r.latestArticles(feed.ID, domain.ArticleFilter{}, num), nil
+}
+
+// ListArticlesByFeed returns the N most recent articles for a feed that pass the filter
+func (r *MemoryRepository) ListArticlesByFeed(ctx context.Context, feedID string, filter domain.ArticleFilter, limit int) ([]domain.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.latestArticles(feedID, filter, limit), nil
+}
+
+// latestArticles must be called with the lock held
+func (r *MemoryRepository) latestArticles(feedID string, filter domain.ArticleFilter, limit int) []domain.Article {
+	var articles []domain.Article
+	for _, a := range r.articles {
+		if a.FeedID == feedID && matchesFilter(a, filter) {
+			articles = append(articles, a)
+		}
+	}
+	sort.SliceStable(articles, func(i, j int) bool { return articles[i].PublishedAt.After(articles[j].PublishedAt) })
+	if limit >= 0 && len(articles) > limit {
+		articles = articles[:limit]
+	}
+	return articles
+}
+
+func matchesFilter(a domain.Article, filter domain.ArticleFilter) bool {
+	return (!filter.Unread || !a.Read) && (!filter.Starred || a.Starred)
+}
+
+func (r *MemoryRepository) SetArticleRead(ctx context.Context, id string, read bool) error {
+	return r.updateArticle(ctx, id, func(a *domain.Article) { a.Read = read })
+}
+
+func (r *MemoryRepository) SetArticleStarred(ctx context.Context, id string, starred bool) error {
+	return r.updateArticle(ctx, id, func(a *domain.Article) { a.Starred = starred })
+}
+
+func (r *MemoryRepository) updateArticle(ctx context.Context, id string, update func(a *domain.Article)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for link, a := range r.articles {
+		if a.ID == id {
+			update(&a)
+			r.articles[link] = a
+			return nil
+		}
+	}
+	return fmt.Errorf("The article is not present in db!")
+}
+
+// MarkFeedRead marks every article of a feed as read and returns how many were unread
+func (r *MemoryRepository) MarkFeedRead(ctx context.Context, feedID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	marked := 0
+	for link, a := range r.articles {
+		if a.FeedID == feedID && !a.Read {
+			a.Read = true
+			r.articles[link] = a
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+// CountUnreadArticles returns the number of unread articles by feed id, feeds without any are left out
+func (r *MemoryRepository) CountUnreadArticles(ctx context.Context) (map[string]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, a := range r.articles {
+		if !a.Read {
+			counts[a.FeedID]++
+		}
+	}
+	return counts, nil
+}
+
+// Timeline merges the articles of several feeds, newest first, one page at a time
+func (r *MemoryRepository) Timeline(ctx context.Context, q domain.TimelineQuery) ([]domain.TimelineEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := make(map[string]bool, len(q.FeedNames))
+	for _, name := range q.FeedNames {
+		wanted[name] = true
+	}
+
+	var entries []domain.TimelineEntry
+	for _, a := range r.articles {
+		feed := r.feeds[a.FeedID]
+		switch {
+		case len(wanted) > 0 && !wanted[feed.Name]:
+		case !q.Feeds.Match(feed):
+		case !q.Since.IsZero() && a.PublishedAt.Before(q.Since):
+		case !q.Until.IsZero() && !a.PublishedAt.Before(q.Until):
+		case !matchesFilter(a, q.Filter):
+		case q.After != nil && !comesAfter(a, *q.After):
+		default:
+			entries = append(entries, domain.TimelineEntry{Article: a, FeedName: feed.Name})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return comesAfter(entries[j].Article, domain.CursorOf(entries[i].Article))
+	})
+	if q.Limit >= 0 && len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+	return entries, nil
+}
+
+// comesAfter reports whether the article comes after the cursor in the newest-first timeline
+func comesAfter(a domain.Article, c domain.TimelineCursor) bool {
+	if !a.PublishedAt.Equal(c.PublishedAt) {
+		return a.PublishedAt.Before(c.PublishedAt)
+	}
+	return a.ID < c.ID
+}
+
+// Weights of the title, description and content matches, the same as the default ones of ts_rank
+var searchWeights = []float64{1.0, 0.4, 0.2}
+
+// snippetWords is how many words around the first match make up a snippet
+const snippetWords = 30
+
+// SearchArticles matches the words of the query case-insensitively in the title, description and content.
+// There is no stemming, so it finds fewer articles than the Postgres and SQLite indexes
+func (r *MemoryRepository) SearchArticles(ctx context.Context, q domain.SearchQuery) ([]domain.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	words := strings.Fields(strings.ToLower(strings.ReplaceAll(q.Text, `"`, " ")))
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []domain.SearchResult
+	for _, a := range r.articles {
+		feed := r.feeds[a.FeedID]
+		if q.FeedName != "" && feed.Name != q.FeedName {
+			continue
+		}
+		if !q.Since.IsZero() && a.PublishedAt.Before(q.Since) {
+			continue
+		}
+
+		fields := []string{a.Title, utils.StripHTML(a.Description), utils.StripHTML(a.Content)}
+		rank, ok := rankMatch(fields, words)
+		if !ok {
+			continue
+		}
+
+		text := fields[2]
+		if text == "" {
+			text = fields[1]
+		}
+		if text == "" {
+			text = fields[0]
+		}
+		results = append(results, domain.SearchResult{
+			Article:  a,
+			FeedName: feed.Name,
+			Rank:     rank,
+			Snippet:  snippet(text, words),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Article.PublishedAt.After(results[j].Article.PublishedAt)
+	})
+	results = results[min(q.Offset, len(results)):]
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results, nil
+}
+
+// rankMatch reports whether every word occurs in one of the fields and scores the weighted occurrences
+func rankMatch(fields []string, words []string) (float64, bool) {
+	rank := 0.0
+	for _, w := range words {
+		found := false
+		for i, f := range fields {
+			if n := strings.Count(strings.ToLower(f), w); n > 0 {
+				rank += searchWeights[i] * float64(n)
+				found = true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return rank, true
+}
+
+// snippet cuts a window of words around the first match and highlights the matching ones
+func snippet(text string, words []string) string {
+	tokens := strings.Fields(text)
+	matches := func(token string) bool {
+		token = strings.ToLower(token)
+		for _, w := range words {
+			if strings.Contains(token, w) {
+				return true
+			}
+		}
+		return false
+	}
+
+	start := 0
+	for i, t := range tokens {
+		if matches(t) {
+			start = max(0, i-snippetWords/3)
+			break
+		}
+	}
+	end := min(len(tokens), start+snippetWords)
+
+	out := make([]string, 0, end-start+2)
+	if start > 0 {
+		out = append(out, "...")
+	}
+	for _, t := range tokens[start:end] {
+		if matches(t) {
+			t = domain.HighlightStart + t + domain.HighlightStop
+		}
+		out = append(out, t)
+	}
+	if end < len(tokens) {
+		out = append(out, "...")
+	}
+	return strings.Join(out, " ")
+}
+
+// -------------------------------------------------------------Fetch jobs--------------------------------------------------------------------
+
+// EnqueueFetchJob queues a feed for fetching. If the feed is already queued its priority is raised when needed
+func (r *MemoryRepository) EnqueueFetchJob(ctx context.Context, feedID string, priority int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.feeds[feedID]; !ok {
+		return fmt.Errorf("feed %s does not exist", feedID)
+	}
+
+	now := time.Now()
+	for id, job := range r.jobs {
+		if job.Feed.ID == feedID && (job.State == domain.JobQueued || job.State == domain.JobRunning) {
+			job.Priority = max(job.Priority, priority)
+			job.UpdatedAt = now
+			r.jobs[id] = job
+			return nil
+		}
+	}
+
+	r.nextJobID++
+	r.jobs[r.nextJobID] = domain.FetchJob{
+		ID:        r.nextJobID,
+		Feed:      domain.Feed{ID: feedID},
+		State:     domain.JobQueued,
+		Priority:  priority,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return nil
+}
+
+// ClaimFetchJob leases the most urgent queued job, or a running job whose lease has expired
+func (r *MemoryRepository) ClaimFetchJob(ctx context.Context, lease time.Duration) (domain.FetchJob, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.FetchJob{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var best domain.FetchJob
+	found := false
+	for id, job := range r.jobs {
+		expired := job.State == domain.JobRunning && job.LeasedUntil.Before(now)
+		if expired && job.Attempts >= domain.MaxJobAttempts {
+			// The lease ran out on the last allowed attempt, the job is given up
+			job.State = domain.JobFailed
+			job.LeasedUntil = time.Time{}
+			job.LastError = domain.AbandonedJobError
+			job.UpdatedAt = now
+			r.jobs[id] = job
+			continue
+		}
+		if job.State != domain.JobQueued && !expired {
+			continue
+		}
+		if !found || job.Priority > best.Priority ||
+			(job.Priority == best.Priority && (job.CreatedAt.Before(best.CreatedAt) ||
+				job.CreatedAt.Equal(best.CreatedAt) && job.ID < best.ID)) {
+			best = job
+			found = true
+		}
+	}
+	if !found {
+		return domain.FetchJob{}, domain.ErrNoJobs
+	}
+
+	best.State = domain.JobRunning
+	best.Attempts++
+	best.LeasedUntil = now.Add(lease)
+	best.UpdatedAt = now
+	r.jobs[best.ID] = best
+
+	best.Feed = r.feeds[best.Feed.ID]
+	return best, nil
+}
+
+func (r *MemoryRepository) CompleteFetchJob(ctx context.Context, id int64) error {
+	return r.finishJob(ctx, id, domain.JobDone, "")
+}
+
+func (r *MemoryRepository) FailFetchJob(ctx context.Context, id int64, reason string) error {
+	return r.finishJob(ctx, id, domain.JobFailed, reason)
+}
+
+func (r *MemoryRepository) finishJob(ctx context.Context, id int64, state domain.JobState, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job, ok := r.jobs[id]; ok {
+		job.State = state
+		job.LeasedUntil = time.Time{}
+		if reason != "" {
+			job.LastError = reason
+		}
+		job.UpdatedAt = time.Now()
+		r.jobs[id] = job
+	}
+	return nil
+}
+
+// ReleaseFetchJob puts an unfinished job back in the queue so it is picked up without waiting for its lease
+func (r *MemoryRepository) ReleaseFetchJob(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job, ok := r.jobs[id]; ok && job.State == domain.JobRunning {
+		job.State = domain.JobQueued
+		job.LeasedUntil = time.Time{}
+		job.UpdatedAt = time.Now()
+		r.jobs[id] = job
+	}
+	return nil
+}
+
+func (r *MemoryRepository) CountQueuedFetchJobs(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, job := range r.jobs {
+		if job.State == domain.JobQueued {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// PurgeFetchJobs deletes done and failed jobs that finished before the given moment
+func (r *MemoryRepository) PurgeFetchJobs(ctx context.Context, finishedBefore time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, job := range r.jobs {
+		if (job.State == domain.JobDone || job.State == domain.JobFailed) && job.UpdatedAt.Before(finishedBefore) {
+			delete(r.jobs, id)
+		}
+	}
+	return nil
+}
+
+// -------------------------------------------------------------Share--------------------------------------------------------------------
+
+func (r *MemoryRepository) FetchCliInterval(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.share.initialized() {
+		return "", sql.ErrNoRows
+	}
+	return r.share.interval, nil
+}
+
+func (r *MemoryRepository) SetInterval(ctx context.Context, interval string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.share != nil {
+		r.share.interval = interval
+	}
+	return nil
+}
+
+func (r *MemoryRepository) SetDefaultCliIntervalAndWorkersNum(ctx context.Context, interval string, workersNum int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.share == nil {
+		r.share = &shareRow{}
+	}
+	r.share.interval = interval
+	r.share.workersNum = workersNum
+	return nil
+}
+
+func (r *MemoryRepository) SetWorkers(ctx context.Context, workersNum int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.share != nil {
+		r.share.workersNum = workersNum
+	}
+	return nil
+}
+
+func (r *MemoryRepository) FetchWorkersNumber(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.share.initialized() {
+		return 0, sql.ErrNoRows
+	}
+	return r.share.workersNum, nil
+}
+
+func (r *MemoryRepository) SetPauseState(ctx context.Context, state domain.PauseState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.share == nil {
+		r.share = &shareRow{}
+	}
+	if !state.Paused {
+		state.Until = time.Time{}
+	}
+	r.share.pause = state
+	return nil
+}
+
+func (r *MemoryRepository) FetchPauseState(ctx context.Context) (domain.PauseState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.share == nil {
+		return domain.PauseState{}, sql.ErrNoRows
+	}
+	return r.share.pause, nil
+}
+
+// -------------------------------------------------------------Migrations--------------------------------------------------------------------
+
+// MigrationStatus reports the version the repository was last migrated to. There is no schema,
+// so migrating only moves the version and keeps fetch's schema check working
+func (r *MemoryRepository) MigrationStatus(ctx context.Context, migrations []domain.Migration) (domain.MigrationStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := domain.MigrationStatus{Current: r.schema}
+	for _, m := range migrations {
+		if m.Version <= r.schema {
+			status.Applied = append(status.Applied, m)
+		} else {
+			status.Pending = append(status.Pending, m)
+		}
+		status.Latest = m.Version
+	}
+	return status, nil
+}
+
+func (r *MemoryRepository) MigrateUp(ctx context.Context, migrations []domain.Migration) ([]domain.Migration, error) {
+	status, err := r.MigrationStatus(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(status.Pending) > 0 {
+		r.schema = status.Latest
+	}
+	return status.Pending, nil
+}
+
+func (r *MemoryRepository) MigrateDown(ctx context.Context, migrations []domain.Migration, steps int) ([]domain.Migration, error) {
+	status, err := r.MigrationStatus(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []domain.Migration
+	previous := status.Current
+	for i := len(status.Applied) - 1; i >= 0 && len(reverted) < steps; i-- {
+		reverted = append(reverted, status.Applied[i])
+		previous = 0
+		if i > 0 {
+			previous = status.Applied[i-1].Version
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schema = previous
+	return reverted, nil
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"RSSHub/internal/adapters/memory"
+	"RSSHub/internal/adapters/repotest"
+	"RSSHub/internal/domain"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	repotest.TestRepository(t, func(t *testing.T) domain.Repository {
+		return memory.NewMemoryRepository()
+	})
+}
@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"RSSHub/internal/domain"
+)
+
+// SetFeedRetention stores the feed's own retention rules, a zero policy falls back to the global rules
+func (r *MemoryRepository) SetFeedRetention(ctx context.Context, feedName string, policy domain.RetentionPolicy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feed, ok := r.feedByName(feedName)
+	if !ok {
+		return fmt.Errorf("The feed is not present in db!")
+	}
+	if policy.IsZero() {
+		delete(r.retention, feed.ID)
+	} else {
+		r.retention[feed.ID] = policy
+	}
+	return nil
+}
+
+// ListFeedRetention returns the rules of the feeds that have their own, by feed id
+func (r *MemoryRepository) ListFeedRetention(ctx context.Context) (map[string]domain.RetentionPolicy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	policies := make(map[string]domain.RetentionPolicy, len(r.retention))
+	for id, policy := range r.retention {
+		policies[id] = policy
+	}
+	return policies, nil
+}
+
+// PruneArticles deletes the unstarred articles of a feed beyond its policy and remembers their links,
+// so that they are not stored again by the next fetch. With dryRun it only returns what would be deleted
+func (r *MemoryRepository) PruneArticles(ctx context.Context, feedID string, policy domain.RetentionPolicy, now time.Time, dryRun bool) ([]domain.Article, error) {
+	if policy.IsZero() {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all []domain.Article
+	for _, a := range r.articles {
+		if a.FeedID == feedID {
+			all = append(all, a)
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		if !all[i].PublishedAt.Equal(all[j].PublishedAt) {
+			return all[i].PublishedAt.After(all[j].PublishedAt)
+		}
+		return all[i].CreatedAt.After(all[j].CreatedAt)
+	})
+
+	cutoff := now.Add(-policy.MaxAge)
+	var articles []domain.Article
+	for i, a := range all {
+		if a.Starred {
+			continue
+		}
+		tooMany := policy.MaxArticles > 0 && i >= policy.MaxArticles
+		tooOld := policy.MaxAge > 0 && a.PublishedAt.Before(cutoff)
+		if tooMany || tooOld {
+			articles = append(articles, a)
+		}
+	}
+
+	if dryRun {
+		return articles, nil
+	}
+	for _, a := range articles {
+		delete(r.articles, a.Link)
+		r.remember(feedID, domain.PrunedArticle{Link: a.Link, GUID: a.GUID, PrunedAt: now})
+	}
+	return articles, nil
+}
+
+// remember must be called with the lock held, a link that is already remembered is left as it is
+func (r *MemoryRepository) remember(feedID string, p domain.PrunedArticle) {
+	if _, ok := r.pruned[p.Link]; ok {
+		return
+	}
+	r.pruned[p.Link] = prunedRow{feedID: feedID, PrunedArticle: p}
+	if p.GUID != "" {
+		r.prunedIDs[p.GUID] = feedID
+	}
+}
+
+// ListPrunedArticles returns what is remembered of the pruned articles of a feed, oldest first
+func (r *MemoryRepository) ListPrunedArticles(ctx context.Context, feedID string) ([]domain.PrunedArticle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pruned []domain.PrunedArticle
+	for _, p := range r.pruned {
+		if p.feedID == feedID {
+			pruned = append(pruned, p.PrunedArticle)
+		}
+	}
+	sort.Slice(pruned, func(i, j int) bool {
+		if !pruned[i].PrunedAt.Equal(pruned[j].PrunedAt) {
+			return pruned[i].PrunedAt.Before(pruned[j].PrunedAt)
+		}
+		return pruned[i].Link < pruned[j].Link
+	})
+	return pruned, nil
+}
+
+// AddPrunedArticles remembers articles pruned elsewhere, e.g. in a restored backup, so that fetching
+// the feed does not store them again. Links that are already remembered are left as they are
+func (r *MemoryRepository) AddPrunedArticles(ctx context.Context, feedID string, pruned []domain.PrunedArticle) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.feeds[feedID]; !ok {
+		return fmt.Errorf("The feed is not present in db!")
+	}
+	for _, p := range pruned {
+		r.remember(feedID, p)
+	}
+	return nil
+}
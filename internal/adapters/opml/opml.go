@@ -0,0 +1,75 @@
+// Package opml reads and writes subscription lists in the OPML format used by feed readers.
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"RSSHub/internal/domain"
+)
+
+// Parse returns the feeds of an OPML document in the order they appear. Nested folders
+// become a single folder path such as "Tech/Go", since a feed sits in one folder
+func Parse(r io.Reader) ([]domain.Feed, error) {
+	var doc domain.OPML
+	dec := xml.NewDecoder(r)
+	// Exports of some readers declare an encoding other than UTF-8 without using it
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OPML: %w", err)
+	}
+
+	var feeds []domain.Feed
+	var walk func(outlines []domain.Outline, folder string)
+	walk = func(outlines []domain.Outline, folder string) {
+		for _, o := range outlines {
+			if o.XMLURL == "" {
+				walk(o.Outlines, joinFolder(folder, outlineName(o)))
+				continue
+			}
+			feeds = append(feeds, domain.Feed{
+				Name:   feedName(o),
+				URL:    strings.TrimSpace(o.XMLURL),
+				Folder: folder,
+				Meta: domain.FeedMeta{
+					Title:   strings.TrimSpace(o.Title),
+					SiteURL: strings.TrimSpace(o.HTMLURL),
+				},
+			})
+		}
+	}
+	walk(doc.Body.Outlines, "")
+	return feeds, nil
+}
+
+func outlineName(o domain.Outline) string {
+	if text := strings.TrimSpace(o.Text); text != "" {
+		return text
+	}
+	return strings.TrimSpace(o.Title)
+}
+
+// feedName is the outline's text, or the host of the feed when the outline has none
+func feedName(o domain.Outline) string {
+	if name := outlineName(o); name != "" {
+		return name
+	}
+	if u, err := url.Parse(strings.TrimSpace(o.XMLURL)); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return strings.TrimSpace(o.XMLURL)
+}
+
+func joinFolder(parent, name string) string {
+	switch {
+	case name == "":
+		return parent
+	case parent == "":
+		return name
+	default:
+		return parent + "/" + name
+	}
+}
@@ -0,0 +1,72 @@
+package opml
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"RSSHub/internal/domain"
+)
+
+// Write stores the feeds as an OPML 2.0 document. Folder paths such as "Tech/Go" become
+// nested outlines, so that Parse reads the same folders back
+func Write(w io.Writer, feeds []domain.Feed, now time.Time) error {
+	sorted := append([]domain.Feed(nil), feeds...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Folder != sorted[j].Folder {
+			return sorted[i].Folder < sorted[j].Folder
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	doc := domain.OPML{Version: "2.0"}
+	doc.Head.Title = "RSSHub subscriptions"
+	doc.Head.DateCreated = now.Format(time.RFC1123Z)
+
+	root := &domain.Outline{}
+	for _, feed := range sorted {
+		parent := root
+		for _, name := range strings.Split(feed.Folder, "/") {
+			if name = strings.TrimSpace(name); name != "" {
+				parent = folder(parent, name)
+			}
+		}
+
+		title := feed.Meta.Title
+		if title == "" {
+			title = feed.Name
+		}
+		parent.Outlines = append(parent.Outlines, domain.Outline{
+			Text:    feed.Name,
+			Title:   title,
+			Type:    "rss",
+			XMLURL:  feed.URL,
+			HTMLURL: feed.Meta.SiteURL,
+		})
+	}
+	doc.Body.Outlines = root.Outlines
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// folder returns the folder outline of the given name under parent, adding it when missing
+func folder(parent *domain.Outline, name string) *domain.Outline {
+	for i := range parent.Outlines {
+		if o := &parent.Outlines[i]; o.XMLURL == "" && o.Text == name {
+			return o
+		}
+	}
+	parent.Outlines = append(parent.Outlines, domain.Outline{Text: name, Title: name})
+	return &parent.Outlines[len(parent.Outlines)-1]
+}
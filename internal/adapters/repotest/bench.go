@@ -0,0 +1,92 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"RSSHub/internal/domain"
+)
+
+// BenchItems is the size of the feed the ingestion benchmarks store on every iteration
+const BenchItems = 500
+
+// BenchmarkIngestFeed stores a feed of BenchItems new articles per iteration with IngestFeed
+func BenchmarkIngestFeed(b *testing.B, repo domain.Repository) {
+	ctx := context.Background()
+	feedID := benchFeed(b, ctx, repo)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		articles := benchArticles(feedID, i)
+		b.StartTimer()
+
+		stats, err := repo.IngestFeed(ctx, feedID, articles, time.Now())
+		if err != nil {
+			b.Fatal(err)
+		}
+		if stats.New != BenchItems {
+			b.Fatalf("stored %d new articles, want %d", stats.New, BenchItems)
+		}
+	}
+	reportItems(b)
+}
+
+// BenchmarkAddArticleLoop stores the same feeds the way the worker did before IngestFeed:
+// one AddArticle per item, then UpdateFeedTimestamp
+func BenchmarkAddArticleLoop(b *testing.B, repo domain.Repository) {
+	ctx := context.Background()
+	feedID := benchFeed(b, ctx, repo)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		articles := benchArticles(feedID, i)
+		b.StartTimer()
+
+		for _, a := range articles {
+			if _, err := repo.AddArticle(ctx, a); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := repo.UpdateFeedTimestamp(ctx, feedID, time.Now()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportItems(b)
+}
+
+func benchFeed(b *testing.B, ctx context.Context, repo domain.Repository) string {
+	b.Helper()
+	if err := repo.AddFeed(ctx, domain.Feed{Name: "bench", URL: "http://bench.example/rss", CreatedAt: base, UpdatedAt: base}); err != nil {
+		b.Fatal(err)
+	}
+	feed, err := repo.ListFeedByName(ctx, "bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return feed.ID
+}
+
+// benchArticles returns BenchItems articles whose links are new in every round
+func benchArticles(feedID string, round int) []domain.Article {
+	articles := make([]domain.Article, BenchItems)
+	for i := range articles {
+		articles[i] = domain.Article{
+			CreatedAt:   base,
+			UpdatedAt:   base,
+			Title:       fmt.Sprintf("Article %d of round %d", i, round),
+			Link:        fmt.Sprintf("http://bench.example/%d/%d", round, i),
+			Description: "A short description of the article, about as long as the usual ones.",
+			PublishedAt: base.Add(time.Duration(i) * time.Minute),
+			FeedID:      feedID,
+		}
+	}
+	return articles
+}
+
+func reportItems(b *testing.B) {
+	b.ReportMetric(float64(b.N*BenchItems)/b.Elapsed().Seconds(), "items/s")
+}
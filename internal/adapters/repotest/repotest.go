@@ -0,0 +1,1206 @@
+// Package repotest checks that a domain.Repository behaves the way the rest of the app expects,
+// in the spirit of testing/fstest. Every storage backend should pass the same suite:
+//
+//	repotest.TestRepository(t, func(t *testing.T) domain.Repository {
+//		return memory.NewMemoryRepository()
+//	})
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"RSSHub/internal/domain"
+)
+
+// TestRepository runs the conformance suite. newRepo must return an empty repository with
+// an up-to-date schema each time it is called; the suite closes it when a subtest ends
+func TestRepository(t *testing.T, newRepo func(t *testing.T) domain.Repository) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, repo domain.Repository)
+	}{
+		{"FeedNamesAreUnique", testFeedNamesAreUnique},
+		{"MissingFeed", testMissingFeed},
+		{"ListFeedsOrder", testListFeedsOrder},
+		{"ListDueFeeds", testListDueFeeds},
+		{"DeleteFeedCascades", testDeleteFeedCascades},
+		{"FoldersAndTags", testFoldersAndTags},
+		{"UpdateFeed", testUpdateFeed},
+		{"FeedMeta", testFeedMeta},
+		{"ArticleUpsert", testArticleUpsert},
+		{"IngestFeed", testIngestFeed},
+		{"ListArticlesOrder", testListArticlesOrder},
+		{"SearchArticles", testSearchArticles},
+		{"ReadAndStarred", testReadAndStarred},
+		{"Timeline", testTimeline},
+		{"Retention", testRetention},
+		{"PruneArticles", testPruneArticles},
+		{"PruneByGUID", testPruneByGUID},
+		{"PrunedArticles", testPrunedArticles},
+		{"JobQueue", testJobQueue},
+		{"JobLease", testJobLease},
+		{"JobMaxAttempts", testJobMaxAttempts},
+		{"Share", testShare},
+		{"PauseOnEmptyStore", testPauseOnEmptyStore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			t.Cleanup(func() { repo.Close() })
+			tt.run(t, context.Background(), repo)
+		})
+	}
+}
+
+// base is a fixed moment in the past, rounded so every backend stores it without loss
+var base = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func addFeed(t *testing.T, ctx context.Context, repo domain.Repository, name string, createdAt time.Time) domain.Feed {
+	t.Helper()
+	feed := domain.Feed{Name: name, URL: "https://example.com/" + name, CreatedAt: createdAt, UpdatedAt: createdAt}
+	if err := repo.AddFeed(ctx, feed); err != nil {
+		t.Fatalf("AddFeed(%q): %v", name, err)
+	}
+	stored, err := repo.ListFeedByName(ctx, name)
+	if err != nil {
+		t.Fatalf("ListFeedByName(%q): %v", name, err)
+	}
+	return stored
+}
+
+func article(link, title string, publishedAt time.Time) domain.Article {
+	return domain.Article{
+		CreatedAt:   base,
+		UpdatedAt:   base,
+		Title:       title,
+		Link:        link,
+		Description: "about " + title,
+		PublishedAt: publishedAt,
+	}
+}
+
+func feedNames(feeds []domain.Feed) []string {
+	names := make([]string, len(feeds))
+	for i, f := range feeds {
+		names[i] = f.Name
+	}
+	return names
+}
+
+func articleLinks(articles []domain.Article) []string {
+	links := make([]string, len(articles))
+	for i, a := range articles {
+		links[i] = a.Link
+	}
+	return links
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// -------------------------------------------------------------Feeds--------------------------------------------------------------------
+
+func testFeedNamesAreUnique(t *testing.T, ctx context.Context, repo domain.Repository) {
+	first := addFeed(t, ctx, repo, "news", base)
+	if first.ID == "" {
+		t.Fatal("stored feed has no id")
+	}
+
+	// A second feed with the same name is ignored, not an error
+	dup := domain.Feed{Name: "news", URL: "https://other.example.com", CreatedAt: base, UpdatedAt: base}
+	if err := repo.AddFeed(ctx, dup); err != nil {
+		t.Fatalf("AddFeed of a duplicate name: %v", err)
+	}
+
+	feeds, err := repo.ListFeeds(ctx, domain.FeedFilter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 || feeds[0].URL != first.URL {
+		t.Fatalf("feeds after duplicate add = %+v, want only the first one", feeds)
+	}
+}
+
+func testMissingFeed(t *testing.T, ctx context.Context, repo domain.Repository) {
+	if _, err := repo.ListFeedByName(ctx, "nope"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ListFeedByName of a missing feed: err = %v, want sql.ErrNoRows", err)
+	}
+	if err := repo.DeleteFeed(ctx, "nope"); err == nil {
+		t.Error("DeleteFeed of a missing feed succeeded")
+	}
+	if _, err := repo.ListFeeds(ctx, domain.FeedFilter{}, -1); err == nil {
+		t.Error("ListFeeds with a negative limit succeeded")
+	}
+}
+
+func testListFeedsOrder(t *testing.T, ctx context.Context, repo domain.Repository) {
+	addFeed(t, ctx, repo, "old", base)
+	addFeed(t, ctx, repo, "new", base.Add(2*time.Hour))
+	addFeed(t, ctx, repo, "mid", base.Add(time.Hour))
+
+	feeds, err := repo.ListFeeds(ctx, domain.FeedFilter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := feedNames(feeds), []string{"new", "mid", "old"}; !equal(got, want) {
+		t.Errorf("ListFeeds(0) = %v, want %v", got, want)
+	}
+
+	feeds, err = repo.ListFeeds(ctx, domain.FeedFilter{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := feedNames(feeds), []string{"new", "mid"}; !equal(got, want) {
+		t.Errorf("ListFeeds(2) = %v, want %v", got, want)
+	}
+}
+
+func testListDueFeeds(t *testing.T, ctx context.Context, repo domain.Repository) {
+	a := addFeed(t, ctx, repo, "a", base)
+	b := addFeed(t, ctx, repo, "b", base)
+	addFeed(t, ctx, repo, "c", base)
+
+	if err := repo.UpdateFeedTimestamp(ctx, a.ID, base.Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateFeedTimestamp(ctx, b.ID, base.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := repo.ListDueFeeds(ctx, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := feedNames(feeds), []string{"c", "a"}; !equal(got, want) {
+		t.Errorf("ListDueFeeds = %v, want %v (least recently fetched first)", got, want)
+	}
+}
+
+func testDeleteFeedCascades(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "gone", base)
+	if _, err := repo.IngestFeed(ctx, feed.ID, []domain.Article{article("https://example.com/1", "one", base)}, base); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.EnqueueFetchJob(ctx, feed.ID, domain.PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.DeleteFeed(ctx, "gone"); err != nil {
+		t.Fatalf("DeleteFeed: %v", err)
+	}
+
+	articles, err := repo.ListArticlesByFeed(ctx, feed.ID, domain.ArticleFilter{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != 0 {
+		t.Errorf("articles of a deleted feed = %v, want none", articleLinks(articles))
+	}
+	if n, err := repo.CountQueuedFetchJobs(ctx); err != nil || n != 0 {
+		t.Errorf("queued jobs after delete = %d, %v; want 0", n, err)
+	}
+
+	// The link is free again for another feed
+	other := addFeed(t, ctx, repo, "other", base)
+	status, err := repo.AddArticle(ctx, domain.Article{FeedID: other.ID, Title: "one", Link: "https://example.com/1", CreatedAt: base, UpdatedAt: base, PublishedAt: base})
+	if err != nil || status != domain.ArticleNew {
+		t.Errorf("AddArticle after delete = %v, %v; want new", status, err)
+	}
+}
+
+// -------------------------------------------------------------Articles--------------------------------------------------------------------
+
+func testFoldersAndTags(t *testing.T, ctx context.Context, repo domain.Repository) {
+	for _, feed := range []domain.Feed{
+		{Name: "go", Folder: "tech", Tags: []string{"lang", "daily"}},
+		{Name: "rust", Folder: "tech", Tags: []string{"lang"}},
+		{Name: "bbc", Folder: "news"},
+	} {
+		feed.URL = "https://example.com/" + feed.Name
+		feed.CreatedAt, feed.UpdatedAt = base, base
+		if err := repo.AddFeed(ctx, feed); err != nil {
+			t.Fatalf("AddFeed(%q): %v", feed.Name, err)
+		}
+	}
+
+	feed, err := repo.ListFeedByName(ctx, "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Folder != "tech" || !slices.Equal(feed.Tags, []string{"daily", "lang"}) {
+		t.Errorf("stored feed has folder %q and tags %v, want tech and [daily lang]", feed.Folder, feed.Tags)
+	}
+
+	names := func(filter domain.FeedFilter) []string {
+		t.Helper()
+		feeds, err := repo.ListFeeds(ctx, filter, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, f := range feeds {
+			names = append(names, f.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	if got := names(domain.FeedFilter{Folder: "tech"}); !slices.Equal(got, []string{"go", "rust"}) {
+		t.Errorf("feeds in folder tech = %v, want [go rust]", got)
+	}
+	if got := names(domain.FeedFilter{Folder: "tech", Tag: "daily"}); !slices.Equal(got, []string{"go"}) {
+		t.Errorf("feeds in folder tech tagged daily = %v, want [go]", got)
+	}
+
+	if err := repo.TagFeed(ctx, "bbc", []string{"daily", "world"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.TagFeed(ctx, "bbc", []string{"daily"}); err != nil {
+		t.Fatalf("tagging a feed twice: %v", err)
+	}
+	if err := repo.UntagFeed(ctx, "go", []string{"daily", "unknown"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(domain.FeedFilter{Tag: "daily"}); !slices.Equal(got, []string{"bbc"}) {
+		t.Errorf("feeds tagged daily = %v, want [bbc]", got)
+	}
+	if err := repo.TagFeed(ctx, "nope", []string{"daily"}); err == nil {
+		t.Error("tagging a missing feed succeeded")
+	}
+
+	// Tags go away with the feed
+	if err := repo.DeleteFeed(ctx, "bbc"); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(domain.FeedFilter{Tag: "world"}); len(got) != 0 {
+		t.Errorf("feeds tagged world after delete = %v, want none", got)
+	}
+}
+
+func testUpdateFeed(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "old", base)
+	addFeed(t, ctx, repo, "other", base)
+	if _, err := repo.IngestFeed(ctx, feed.ID, []domain.Article{
+		article("https://example.com/1", "one", base),
+	}, base); err != nil {
+		t.Fatal(err)
+	}
+
+	update := domain.Feed{Name: "new", URL: "https://example.com/moved", Folder: "tech", Interval: 2 * time.Hour}
+	if err := repo.UpdateFeed(ctx, "old", update); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.ListFeedByName(ctx, "old"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("old name still resolves: err = %v", err)
+	}
+	stored, err := repo.ListFeedByName(ctx, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ID != feed.ID || stored.URL != update.URL || stored.Folder != "tech" || stored.Interval != 2*time.Hour {
+		t.Errorf("updated feed = %+v, want id %s with the new URL, folder and interval", stored, feed.ID)
+	}
+	if !stored.CreatedAt.Equal(base) {
+		t.Errorf("CreatedAt changed to %v", stored.CreatedAt)
+	}
+
+	// The articles stay with the renamed feed
+	articles, err := repo.ListArticles(ctx, "new", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != 1 {
+		t.Errorf("renamed feed has %d articles, want 1", len(articles))
+	}
+
+	update.Name = "other"
+	if err := repo.UpdateFeed(ctx, "new", update); err == nil {
+		t.Error("renaming onto an existing feed succeeded")
+	}
+	if err := repo.UpdateFeed(ctx, "nope", update); err == nil {
+		t.Error("updating a missing feed succeeded")
+	}
+}
+
+func testFeedMeta(t *testing.T, ctx context.Context, repo domain.Repository) {
+	meta := domain.FeedMeta{
+		Title:       "Example",
+		SiteURL:     "https://example.com/",
+		Description: "All the examples",
+		Language:    "en",
+		ImageURL:    "https://example.com/logo.png",
+	}
+	feed := domain.Feed{Name: "meta", URL: "https://example.com/rss", CreatedAt: base, UpdatedAt: base, Meta: meta}
+	if err := repo.AddFeed(ctx, feed); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repo.ListFeedByName(ctx, "meta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Meta != meta {
+		t.Errorf("metadata after AddFeed = %+v, want %+v", stored.Meta, meta)
+	}
+
+	meta.Title = "Example, renamed"
+	meta.IconURL = "https://example.com/favicon.ico"
+	meta.IconCheckedAt = base.Add(time.Hour)
+	if err := repo.UpdateFeedMeta(ctx, stored.ID, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	// Workers get the whole feed with the job
+	if err := repo.EnqueueFetchJob(ctx, stored.ID, domain.PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	job, err := repo.ClaimFetchJob(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := job.Feed.Meta
+	if got.Title != meta.Title || got.IconURL != meta.IconURL || !got.IconCheckedAt.Equal(meta.IconCheckedAt) {
+		t.Errorf("metadata of the claimed feed = %+v, want %+v", got, meta)
+	}
+}
+
+func testArticleUpsert(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "blog", base)
+	a := article("https://example.com/post", "first", base)
+	a.FeedID = feed.ID
+
+	steps := []struct {
+		title string
+		want  domain.ArticleStatus
+	}{
+		{"first", domain.ArticleNew},
+		{"first", domain.ArticleSkipped},
+		{"renamed", domain.ArticleUpdated},
+	}
+	for _, step := range steps {
+		a.Title = step.title
+		a.Description = "about first"
+		status, err := repo.AddArticle(ctx, a)
+		if err != nil {
+			t.Fatalf("AddArticle(%q): %v", step.title, err)
+		}
+		if status != step.want {
+			t.Errorf("AddArticle(%q) status = %v, want %v", step.title, status, step.want)
+		}
+	}
+
+	articles, err := repo.ListArticles(ctx, "blog", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != 1 || articles[0].Title != "renamed" || articles[0].FeedID != feed.ID {
+		t.Errorf("stored articles = %+v, want one renamed article", articles)
+	}
+}
+
+func testIngestFeed(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "daily", base)
+	fetchedAt := base.Add(time.Hour)
+
+	batch := []domain.Article{
+		article("https://example.com/1", "one", base),
+		article("https://example.com/2", "two", base),
+		article("https://example.com/1", "one", base),
+	}
+	stats, err := repo.IngestFeed(ctx, feed.ID, batch, fetchedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (domain.IngestStats{New: 2, Skipped: 1}); stats != want {
+		t.Errorf("first ingest = %+v, want %+v", stats, want)
+	}
+
+	stored, err := repo.ListFeedByName(ctx, "daily")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.UpdatedAt.Equal(fetchedAt) {
+		t.Errorf("feed updated_at = %v, want %v", stored.UpdatedAt, fetchedAt)
+	}
+
+	batch = []domain.Article{
+		article("https://example.com/1", "one", base),
+		article("https://example.com/2", "two, edited", base),
+		article("https://example.com/3", "three", base),
+	}
+	stats, err = repo.IngestFeed(ctx, feed.ID, batch, fetchedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (domain.IngestStats{New: 1, Updated: 1, Skipped: 1}); stats != want {
+		t.Errorf("second ingest = %+v, want %+v", stats, want)
+	}
+}
+
+func testListArticlesOrder(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "feed", base)
+	other := addFeed(t, ctx, repo, "other", base)
+
+	batch := []domain.Article{
+		article("https://example.com/old", "old", base),
+		article("https://example.com/new", "new", base.Add(2*time.Hour)),
+		article("https://example.com/mid", "mid", base.Add(time.Hour)),
+	}
+	if _, err := repo.IngestFeed(ctx, feed.ID, batch, base); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.IngestFeed(ctx, other.ID, []domain.Article{article("https://example.com/x", "x", base)}, base); err != nil {
+		t.Fatal(err)
+	}
+
+	articles, err := repo.ListArticles(ctx, "feed", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://example.com/new", "https://example.com/mid"}
+	if got := articleLinks(articles); !equal(got, want) {
+		t.Errorf("ListArticles = %v, want %v", got, want)
+	}
+
+	articles, err = repo.ListArticlesByFeed(ctx, feed.ID, domain.ArticleFilter{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, "https://example.com/old")
+	if got := articleLinks(articles); !equal(got, want) {
+		t.Errorf("ListArticlesByFeed = %v, want %v", got, want)
+	}
+}
+
+func testSearchArticles(t *testing.T, ctx context.Context, repo domain.Repository) {
+	space := addFeed(t, ctx, repo, "space", base)
+	garden := addFeed(t, ctx, repo, "garden", base)
+
+	inTitle := article("https://example.com/title", "Rocket launch delayed", base.Add(time.Hour))
+	inContent := article("https://example.com/content", "Weekly news", base.Add(2*time.Hour))
+	inContent.Content = "<p>The <b>rocket</b> team met on Monday.</p>"
+	old := article("https://example.com/old", "Old rocket story", base.Add(-48*time.Hour))
+	if _, err := repo.IngestFeed(ctx, space.ID, []domain.Article{inTitle, inContent, old}, base); err != nil {
+		t.Fatal(err)
+	}
+	tomatoes := article("https://example.com/tomatoes", "Tomatoes", base)
+	tomatoes.Content = "Plant them before the rocket salad"
+	if _, err := repo.IngestFeed(ctx, garden.ID, []domain.Article{tomatoes}, base); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := repo.SearchArticles(ctx, domain.SearchQuery{Text: "rocket", FeedName: "space", Since: base, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := make([]string, len(results))
+	for i, res := range results {
+		links[i] = res.Article.Link
+	}
+	if want := []string{inTitle.Link, inContent.Link}; !equal(links, want) {
+		t.Fatalf("search results = %v, want %v (title matches first, other feeds and older articles left out)", links, want)
+	}
+	if results[0].FeedName != "space" || results[0].Rank <= results[1].Rank {
+		t.Errorf("first result = %+v, want the space feed and a higher rank than %v", results[0], results[1].Rank)
+	}
+	if !strings.Contains(results[1].Snippet, domain.HighlightStart+"rocket"+domain.HighlightStop) {
+		t.Errorf("snippet %q does not highlight the match", results[1].Snippet)
+	}
+	if strings.Contains(results[1].Snippet, "<p>") {
+		t.Errorf("snippet %q contains HTML", results[1].Snippet)
+	}
+
+	// Every word has to match
+	results, err = repo.SearchArticles(ctx, domain.SearchQuery{Text: "rocket launch", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Article.Link != inTitle.Link {
+		t.Errorf("search for two words = %+v, want only %s", results, inTitle.Link)
+	}
+
+	results, err = repo.SearchArticles(ctx, domain.SearchQuery{Text: "rocket", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Errorf("search with limit 2 returned %d results", len(results))
+	}
+
+	// Pages follow the ranking: the title match first, then the rest by rank
+	all, err := repo.SearchArticles(ctx, domain.SearchQuery{Text: "rocket", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := repo.SearchArticles(ctx, domain.SearchQuery{Text: "rocket", Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 || len(page) != 2 || page[0].Article.Link != all[1].Article.Link || page[1].Article.Link != all[2].Article.Link {
+		t.Errorf("second page = %v, want results 2 and 3 of %v", searchLinks(page), searchLinks(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Rank > all[i-1].Rank {
+			t.Errorf("results are not ordered by rank: %v after %v", all[i].Rank, all[i-1].Rank)
+		}
+	}
+
+	// Marking an article leaves it searchable
+	if err := repo.SetArticleRead(ctx, all[0].Article.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := repo.SearchArticles(ctx, domain.SearchQuery{Text: "launch", Limit: 10}); err != nil || len(results) != 1 {
+		t.Errorf("search after marking read = %v, %v; want the article", searchLinks(results), err)
+	}
+}
+
+func searchLinks(results []domain.SearchResult) []string {
+	links := make([]string, len(results))
+	for i, res := range results {
+		links[i] = res.Article.Link
+	}
+	return links
+}
+
+func testReadAndStarred(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "feed", base)
+	other := addFeed(t, ctx, repo, "other", base)
+	batch := []domain.Article{
+		article("https://example.com/1", "one", base),
+		article("https://example.com/2", "two", base.Add(time.Hour)),
+		article("https://example.com/3", "three", base.Add(2*time.Hour)),
+	}
+	if _, err := repo.IngestFeed(ctx, feed.ID, batch, base); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.IngestFeed(ctx, other.ID, []domain.Article{article("https://example.com/x", "x", base)}, base); err != nil {
+		t.Fatal(err)
+	}
+
+	articles, err := repo.ListArticlesByFeed(ctx, feed.ID, domain.ArticleFilter{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range articles {
+		if a.Read || a.Starred {
+			t.Fatalf("new article %s is read or starred", a.Link)
+		}
+	}
+	newest, middle := articles[0], articles[1]
+
+	if err := repo.SetArticleRead(ctx, newest.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetArticleStarred(ctx, middle.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetArticleRead(ctx, "00000000-0000-4000-8000-000000000000", true); err == nil {
+		t.Error("SetArticleRead of a missing article succeeded")
+	}
+	if err := repo.SetArticleStarred(ctx, "not-an-id", true); err == nil {
+		t.Error("SetArticleStarred of a malformed id succeeded")
+	}
+
+	unread, err := repo.ListArticlesByFeed(ctx, feed.ID, domain.ArticleFilter{Unread: true}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := articleLinks(unread), []string{"https://example.com/2", "https://example.com/1"}; !equal(got, want) {
+		t.Errorf("unread articles = %v, want %v", got, want)
+	}
+	starred, err := repo.ListArticlesByFeed(ctx, feed.ID, domain.ArticleFilter{Starred: true}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starred) != 1 || !starred[0].Starred || starred[0].ID != middle.ID {
+		t.Errorf("starred articles = %v, want only %s", articleLinks(starred), middle.Link)
+	}
+
+	counts, err := repo.CountUnreadArticles(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[feed.ID] != 2 || counts[other.ID] != 1 {
+		t.Errorf("unread counts = %v, want 2 for feed and 1 for other", counts)
+	}
+
+	// A refetched article keeps its state
+	changed := batch[2]
+	changed.Title = "three, edited"
+	if _, err := repo.IngestFeed(ctx, feed.ID, []domain.Article{changed}, base); err != nil {
+		t.Fatal(err)
+	}
+	if unread, _ := repo.ListArticlesByFeed(ctx, feed.ID, domain.ArticleFilter{Unread: true}, 10); len(unread) != 2 {
+		t.Errorf("an updated article became unread again: %v", articleLinks(unread))
+	}
+
+	marked, err := repo.MarkFeedRead(ctx, feed.ID)
+	if err != nil || marked != 2 {
+		t.Errorf("MarkFeedRead = %d, %v; want 2", marked, err)
+	}
+	counts, err = repo.CountUnreadArticles(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[feed.ID] != 0 || counts[other.ID] != 1 {
+		t.Errorf("unread counts after mark-all-read = %v, want only 1 for other", counts)
+	}
+
+	// Starred articles survive pruning
+	pruned, err := repo.PruneArticles(ctx, feed.ID, domain.RetentionPolicy{MaxArticles: 1}, base, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := articleLinks(pruned), []string{"https://example.com/1"}; !equal(got, want) {
+		t.Errorf("pruned = %v, want %v (the starred one kept)", got, want)
+	}
+}
+
+func testTimeline(t *testing.T, ctx context.Context, repo domain.Repository) {
+	a := addFeed(t, ctx, repo, "a", base)
+	b := addFeed(t, ctx, repo, "b", base)
+	addFeed(t, ctx, repo, "c", base)
+
+	// Two articles share a publish time, the id breaks the tie
+	if _, err := repo.IngestFeed(ctx, a.ID, []domain.Article{
+		article("https://example.com/a1", "a1", base.Add(1*time.Hour)),
+		article("https://example.com/a3", "a3", base.Add(3*time.Hour)),
+		article("https://example.com/a5", "a5", base.Add(5*time.Hour)),
+	}, base); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.IngestFeed(ctx, b.ID, []domain.Article{
+		article("https://example.com/b3", "b3", base.Add(3*time.Hour)),
+		article("https://example.com/b4", "b4", base.Add(4*time.Hour)),
+	}, base); err != nil {
+		t.Fatal(err)
+	}
+
+	var all []string
+	query := domain.TimelineQuery{Limit: 2}
+	for page := 0; page < 5; page++ {
+		entries, err := repo.Timeline(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			all = append(all, e.Article.Title)
+		}
+		if len(entries) < query.Limit {
+			break
+		}
+		cursor := domain.CursorOf(entries[len(entries)-1].Article)
+		// The cursor goes through the CLI as text
+		cursor, err = domain.ParseTimelineCursor(cursor.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		query.After = &cursor
+	}
+	if len(all) != 5 || all[0] != "a5" || all[1] != "b4" || all[4] != "a1" {
+		t.Fatalf("paged timeline = %v, want a5 b4 (a3 b3 in id order) a1", all)
+	}
+	if all[2] == all[3] {
+		t.Fatalf("paged timeline repeats %s", all[2])
+	}
+
+	entries, err := repo.Timeline(ctx, domain.TimelineQuery{
+		FeedNames: []string{"b", "c"},
+		Since:     base.Add(3 * time.Hour),
+		Until:     base.Add(4 * time.Hour),
+		Limit:     10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Article.Title != "b3" || entries[0].FeedName != "b" {
+		t.Errorf("filtered timeline = %+v, want only b3", entries)
+	}
+
+	if err := repo.TagFeed(ctx, "a", []string{"news"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = repo.Timeline(ctx, domain.TimelineQuery{Feeds: domain.FeedFilter{Tag: "news"}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].FeedName != "a" || entries[2].FeedName != "a" {
+		t.Errorf("timeline of tag news = %+v, want the 3 articles of a", entries)
+	}
+
+	newest, err := repo.Timeline(ctx, domain.TimelineQuery{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetArticleRead(ctx, newest[0].Article.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = repo.Timeline(ctx, domain.TimelineQuery{Filter: domain.ArticleFilter{Unread: true}, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Article.Title != "b4" {
+		t.Errorf("unread timeline starts with %+v, want b4", entries)
+	}
+}
+
+func testRetention(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "feed", base)
+	addFeed(t, ctx, repo, "other", base)
+
+	if err := repo.SetFeedRetention(ctx, "nope", domain.RetentionPolicy{MaxArticles: 1}); err == nil {
+		t.Error("SetFeedRetention of a missing feed succeeded")
+	}
+
+	policy := domain.RetentionPolicy{MaxArticles: 5, MaxAge: 30 * 24 * time.Hour}
+	if err := repo.SetFeedRetention(ctx, "feed", policy); err != nil {
+		t.Fatal(err)
+	}
+	policies, err := repo.ListFeedRetention(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 1 || policies[feed.ID] != policy {
+		t.Errorf("ListFeedRetention = %v, want only %v for the feed", policies, policy)
+	}
+
+	if err := repo.SetFeedRetention(ctx, "feed", domain.RetentionPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	if policies, err := repo.ListFeedRetention(ctx); err != nil || len(policies) != 0 {
+		t.Errorf("ListFeedRetention after reset = %v, %v; want none", policies, err)
+	}
+}
+
+func testPruneArticles(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "feed", base)
+	batch := []domain.Article{
+		article("https://example.com/1", "one", base.Add(-72*time.Hour)),
+		article("https://example.com/2", "two", base.Add(-48*time.Hour)),
+		article("https://example.com/3", "three", base.Add(-time.Hour)),
+		article("https://example.com/4", "four", base),
+	}
+	if _, err := repo.IngestFeed(ctx, feed.ID, batch, base); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keep the 3 newest and nothing older than a day: articles 1 and 2 go
+	policy := domain.RetentionPolicy{MaxArticles: 3, MaxAge: 24 * time.Hour}
+	pruned, err := repo.PruneArticles(ctx, feed.ID, policy, base, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://example.com/2", "https://example.com/1"}
+	if got := articleLinks(pruned); !equal(got, want) {
+		t.Fatalf("dry run = %v, want %v", got, want)
+	}
+	if left, _ := repo.ListArticlesByFeed(ctx, feed.ID, domain.ArticleFilter{}, 10); len(left) != 4 {
+		t.Fatalf("dry run deleted articles, %d left", len(left))
+	}
+
+	pruned, err = repo.PruneArticles(ctx, feed.ID, policy, base, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := articleLinks(pruned); !equal(got, want) {
+		t.Errorf("pruned = %v, want %v", got, want)
+	}
+	left, err := repo.ListArticlesByFeed(ctx, feed.ID, domain.ArticleFilter{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := articleLinks(left), []string{"https://example.com/4", "https://example.com/3"}; !equal(got, want) {
+		t.Errorf("articles left = %v, want %v", got, want)
+	}
+
+	// The next fetch does not bring the pruned articles back
+	stats, err := repo.IngestFeed(ctx, feed.ID, batch, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (domain.IngestStats{Skipped: 4}); stats != want {
+		t.Errorf("ingest after prune = %+v, want %+v", stats, want)
+	}
+	a := batch[0]
+	a.FeedID = feed.ID
+	if status, err := repo.AddArticle(ctx, a); err != nil || status != domain.ArticleSkipped {
+		t.Errorf("AddArticle of a pruned article = %v, %v; want skipped", status, err)
+	}
+
+	if pruned, err := repo.PruneArticles(ctx, feed.ID, domain.RetentionPolicy{}, base, false); err != nil || len(pruned) != 0 {
+		t.Errorf("prune with an empty policy = %v, %v; want nothing", articleLinks(pruned), err)
+	}
+}
+
+func testPruneByGUID(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "feed", base)
+	batch := []domain.Article{
+		article("http://example.com/1", "one", base.Add(-72*time.Hour)),
+		article("http://example.com/2", "two", base.Add(-48*time.Hour)),
+		article("http://example.com/3", "three", base),
+	}
+	batch[0].GUID = "tag:example.com,2024:1"
+	if _, err := repo.IngestFeed(ctx, feed.ID, batch, base); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repo.ListArticlesByFeed(ctx, feed.ID, domain.ArticleFilter{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stored[len(stored)-1].GUID; got != batch[0].GUID {
+		t.Errorf("stored GUID = %q, want %q", got, batch[0].GUID)
+	}
+	if _, err := repo.PruneArticles(ctx, feed.ID, domain.RetentionPolicy{MaxArticles: 1}, base, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The feed moved to https and added tracking parameters: the item with a GUID stays pruned,
+	// the one without falls back to its link and comes back
+	rewritten := make([]domain.Article, len(batch)-1)
+	for i, a := range batch[:2] {
+		a.Link = strings.Replace(a.Link, "http:", "https:", 1) + "?utm_source=rss"
+		rewritten[i] = a
+	}
+	stats, err := repo.IngestFeed(ctx, feed.ID, rewritten, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (domain.IngestStats{New: 1, Skipped: 1}); stats != want {
+		t.Errorf("ingest of rewritten links = %+v, want %+v", stats, want)
+	}
+	a := rewritten[0]
+	a.Link += "&utm_medium=feed"
+	a.FeedID = feed.ID
+	if status, err := repo.AddArticle(ctx, a); err != nil || status != domain.ArticleSkipped {
+		t.Errorf("AddArticle of a pruned GUID = %v, %v; want skipped", status, err)
+	}
+}
+
+// testPrunedArticles lists what a prune remembered and carries it over to another store,
+// as a restored backup does
+func testPrunedArticles(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "feed", base)
+	batch := []domain.Article{
+		article("http://example.com/1", "one", base.Add(-72*time.Hour)),
+		article("http://example.com/2", "two", base.Add(-48*time.Hour)),
+		article("http://example.com/3", "three", base),
+	}
+	batch[0].GUID = "tag:example.com,2024:1"
+	if _, err := repo.IngestFeed(ctx, feed.ID, batch, base); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.PruneArticles(ctx, feed.ID, domain.RetentionPolicy{MaxArticles: 1}, base, false); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := repo.ListPrunedArticles(ctx, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []domain.PrunedArticle{
+		{Link: "http://example.com/1", GUID: "tag:example.com,2024:1", PrunedAt: base},
+		{Link: "http://example.com/2", PrunedAt: base},
+	}
+	if len(pruned) != len(want) {
+		t.Fatalf("ListPrunedArticles = %+v, want %+v", pruned, want)
+	}
+	for i := range want {
+		if pruned[i].Link != want[i].Link || pruned[i].GUID != want[i].GUID || !pruned[i].PrunedAt.Equal(want[i].PrunedAt) {
+			t.Errorf("pruned[%d] = %+v, want %+v", i, pruned[i], want[i])
+		}
+	}
+
+	other := addFeed(t, ctx, repo, "other", base)
+	if pruned, err := repo.ListPrunedArticles(ctx, other.ID); err != nil || len(pruned) != 0 {
+		t.Errorf("ListPrunedArticles of a feed never pruned = %+v, %v; want none", pruned, err)
+	}
+	restored := []domain.PrunedArticle{
+		{Link: "http://other.example.com/1", GUID: "tag:other.example.com,2024:1", PrunedAt: base.Add(-time.Hour)},
+		{Link: "http://other.example.com/2", PrunedAt: base},
+		// Already remembered for the first feed, it stays there
+		{Link: "http://example.com/2", PrunedAt: base.Add(time.Hour)},
+	}
+	if err := repo.AddPrunedArticles(ctx, other.ID, restored); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AddPrunedArticles(ctx, other.ID, restored); err != nil {
+		t.Fatalf("AddPrunedArticles of links already remembered: %v", err)
+	}
+	if pruned, err := repo.ListPrunedArticles(ctx, other.ID); err != nil || len(pruned) != 2 || pruned[0].Link != restored[0].Link {
+		t.Errorf("ListPrunedArticles after AddPrunedArticles = %+v, %v; want the two new links, oldest first", pruned, err)
+	}
+	if pruned, err := repo.ListPrunedArticles(ctx, feed.ID); err != nil || len(pruned) != 2 {
+		t.Errorf("ListPrunedArticles of the first feed = %+v, %v; want it unchanged", pruned, err)
+	}
+
+	// The remembered articles are not stored again, by link or by GUID
+	again := []domain.Article{
+		article("http://other.example.com/2", "two", base),
+		article("https://other.example.com/1?utm_source=rss", "one", base),
+		article("http://other.example.com/3", "three", base),
+	}
+	again[1].GUID = restored[0].GUID
+	stats, err := repo.IngestFeed(ctx, other.ID, again, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (domain.IngestStats{New: 1, Skipped: 2}); stats != want {
+		t.Errorf("ingest of restored pruned articles = %+v, want %+v", stats, want)
+	}
+
+	if err := repo.DeleteFeed(ctx, other.Name); err != nil {
+		t.Fatal(err)
+	}
+	if pruned, err := repo.ListPrunedArticles(ctx, other.ID); err != nil || len(pruned) != 0 {
+		t.Errorf("ListPrunedArticles of a deleted feed = %+v, %v; want none", pruned, err)
+	}
+}
+
+// -------------------------------------------------------------Fetch jobs--------------------------------------------------------------------
+
+func testJobQueue(t *testing.T, ctx context.Context, repo domain.Repository) {
+	low := addFeed(t, ctx, repo, "low", base)
+	high := addFeed(t, ctx, repo, "high", base)
+
+	if _, err := repo.ClaimFetchJob(ctx, time.Minute); !errors.Is(err, domain.ErrNoJobs) {
+		t.Fatalf("ClaimFetchJob on an empty queue: err = %v, want ErrNoJobs", err)
+	}
+
+	for _, step := range []struct {
+		feed     domain.Feed
+		priority int
+	}{
+		{low, domain.PriorityNormal},
+		{high, domain.PriorityNormal},
+		{low, domain.PriorityNormal}, // already queued, no second job
+		{high, domain.PriorityHigh},  // raises the queued job instead
+	} {
+		if err := repo.EnqueueFetchJob(ctx, step.feed.ID, step.priority); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := repo.CountQueuedFetchJobs(ctx); err != nil || n != 2 {
+		t.Fatalf("queued jobs = %d, %v; want 2", n, err)
+	}
+
+	job, err := repo.ClaimFetchJob(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Feed.Name != "high" || job.State != domain.JobRunning || job.Attempts != 1 {
+		t.Errorf("first claimed job = %+v, want the running high priority one", job)
+	}
+	if job.Feed.URL != high.URL {
+		t.Errorf("claimed job feed = %+v, want the full feed", job.Feed)
+	}
+
+	// A running job still blocks a second one for the same feed
+	if err := repo.EnqueueFetchJob(ctx, high.ID, domain.PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repo.CountQueuedFetchJobs(ctx); err != nil || n != 1 {
+		t.Errorf("queued jobs while one runs = %d, %v; want 1", n, err)
+	}
+	if err := repo.CompleteFetchJob(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := repo.ClaimFetchJob(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Feed.Name != "low" {
+		t.Errorf("second claimed job is for %q, want low", next.Feed.Name)
+	}
+	if err := repo.FailFetchJob(ctx, next.ID, "boom"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ClaimFetchJob(ctx, time.Minute); !errors.Is(err, domain.ErrNoJobs) {
+		t.Errorf("ClaimFetchJob after all finished: err = %v, want ErrNoJobs", err)
+	}
+
+	// Finished jobs no longer count as active, so the feed can be queued again
+	if err := repo.EnqueueFetchJob(ctx, high.ID, domain.PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repo.CountQueuedFetchJobs(ctx); err != nil || n != 1 {
+		t.Errorf("queued jobs after requeue = %d, %v; want 1", n, err)
+	}
+
+	if err := repo.PurgeFetchJobs(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repo.CountQueuedFetchJobs(ctx); err != nil || n != 1 {
+		t.Errorf("PurgeFetchJobs removed queued jobs: %d, %v; want 1", n, err)
+	}
+}
+
+func testJobLease(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "slow", base)
+	if err := repo.EnqueueFetchJob(ctx, feed.ID, domain.PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := repo.ClaimFetchJob(ctx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ClaimFetchJob(ctx, time.Hour); !errors.Is(err, domain.ErrNoJobs) {
+		t.Fatalf("a leased job was claimed twice: err = %v", err)
+	}
+
+	// A released job is claimable right away
+	if err := repo.ReleaseFetchJob(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	job, err = repo.ClaimFetchJob(ctx, -time.Second)
+	if err != nil {
+		t.Fatalf("claim after release: %v", err)
+	}
+
+	// The lease above is already over, as if its worker had crashed
+	again, err := repo.ClaimFetchJob(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("claim of an expired lease: %v", err)
+	}
+	if again.ID != job.ID || again.Attempts != 3 {
+		t.Errorf("reclaimed job = %+v, want job %d with 3 attempts", again, job.ID)
+	}
+}
+
+func testJobMaxAttempts(t *testing.T, ctx context.Context, repo domain.Repository) {
+	feed := addFeed(t, ctx, repo, "poison", base)
+	if err := repo.EnqueueFetchJob(ctx, feed.ID, domain.PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every lease runs out, as if the feed crashed or hung its worker each time
+	var first domain.FetchJob
+	for i := 1; i <= domain.MaxJobAttempts; i++ {
+		job, err := repo.ClaimFetchJob(ctx, -time.Second)
+		if err != nil {
+			t.Fatalf("claim %d: %v", i, err)
+		}
+		if i == 1 {
+			first = job
+		}
+		if job.ID != first.ID || job.Attempts != i {
+			t.Fatalf("claim %d = job %d with %d attempts, want job %d with %d", i, job.ID, job.Attempts, first.ID, i)
+		}
+	}
+	if job, err := repo.ClaimFetchJob(ctx, time.Hour); !errors.Is(err, domain.ErrNoJobs) {
+		t.Fatalf("claim after %d attempts = job %d, %v; want the job given up", domain.MaxJobAttempts, job.ID, err)
+	}
+
+	// The failed job no longer blocks the feed from being queued again
+	if err := repo.EnqueueFetchJob(ctx, feed.ID, domain.PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	job, err := repo.ClaimFetchJob(ctx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == first.ID || job.Attempts != 1 {
+		t.Errorf("job after requeue = %d with %d attempts, want a new job", job.ID, job.Attempts)
+	}
+}
+
+// -------------------------------------------------------------Share--------------------------------------------------------------------
+
+func testShare(t *testing.T, ctx context.Context, repo domain.Repository) {
+	if _, err := repo.FetchCliInterval(ctx); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("FetchCliInterval before init: err = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := repo.FetchPauseState(ctx); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("FetchPauseState before init: err = %v, want sql.ErrNoRows", err)
+	}
+	if err := repo.SetDefaultCliIntervalAndWorkersNum(ctx, "3m0s", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetInterval(ctx, "5m0s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetWorkers(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+	if interval, err := repo.FetchCliInterval(ctx); err != nil || interval != "5m0s" {
+		t.Errorf("FetchCliInterval = %q, %v; want 5m0s", interval, err)
+	}
+	if n, err := repo.FetchWorkersNumber(ctx); err != nil || n != 7 {
+		t.Errorf("FetchWorkersNumber = %d, %v; want 7", n, err)
+	}
+
+	until := base.Add(time.Hour)
+	if err := repo.SetPauseState(ctx, domain.PauseState{Paused: true, Until: until}); err != nil {
+		t.Fatal(err)
+	}
+	state, err := repo.FetchPauseState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Paused || !state.Until.Equal(until) {
+		t.Errorf("pause state = %+v, want paused until %v", state, until)
+	}
+
+	if err := repo.SetPauseState(ctx, domain.PauseState{}); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := repo.FetchPauseState(ctx); err != nil || state.Paused || !state.Until.IsZero() {
+		t.Errorf("pause state after resume = %+v, %v; want not paused", state, err)
+	}
+}
+
+// testPauseOnEmptyStore checks that pause and resume work before fetch has
+// ever stored its settings, and that fetch keeps the pause when it starts
+func testPauseOnEmptyStore(t *testing.T, ctx context.Context, repo domain.Repository) {
+	until := base.Add(time.Hour)
+	if err := repo.SetPauseState(ctx, domain.PauseState{Paused: true, Until: until}); err != nil {
+		t.Fatalf("SetPauseState on an empty store: %v", err)
+	}
+	state, err := repo.FetchPauseState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Paused || !state.Until.Equal(until) {
+		t.Errorf("pause state = %+v, want paused until %v", state, until)
+	}
+	if _, err := repo.FetchCliInterval(ctx); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("FetchCliInterval after a pause only: err = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := repo.FetchWorkersNumber(ctx); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("FetchWorkersNumber after a pause only: err = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := repo.SetDefaultCliIntervalAndWorkersNum(ctx, "3m0s", 3); err != nil {
+		t.Fatal(err)
+	}
+	if interval, err := repo.FetchCliInterval(ctx); err != nil || interval != "3m0s" {
+		t.Errorf("FetchCliInterval = %q, %v; want 3m0s", interval, err)
+	}
+	if state, err := repo.FetchPauseState(ctx); err != nil || !state.Paused || !state.Until.Equal(until) {
+		t.Errorf("pause state after fetch started = %+v, %v; want paused until %v", state, err, until)
+	}
+
+	if err := repo.SetPauseState(ctx, domain.PauseState{}); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := repo.FetchPauseState(ctx); err != nil || state.Paused {
+		t.Errorf("pause state after resume = %+v, %v; want not paused", state, err)
+	}
+}
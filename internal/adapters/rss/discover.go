@@ -0,0 +1,144 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// FeedCandidate is a feed advertised by, or found next to, a web page
+type FeedCandidate struct {
+	URL   string
+	Title string
+	// Kind is rss, atom or json
+	Kind string
+}
+
+// Readable tells whether the app can fetch the candidate, only RSS 2.0 for now
+func (c FeedCandidate) Readable() bool {
+	return c.Kind == "rss"
+}
+
+var (
+	ErrNoFeedFound = errors.New("Could not find any feed on this web page")
+	// ErrUnsupportedFeeds is returned when a page only offers Atom or JSON feeds
+	ErrUnsupportedFeeds = errors.New("This web page only offers Atom or JSON feeds, which cannot be read yet")
+	// ErrPickFeed asks to choose among several feeds with --pick
+	ErrPickFeed = errors.New("Several feeds were found on this web page, choose one with --pick N")
+)
+
+// ChooseFeed picks the candidate to add among the feeds of a page. pick is the position of
+// the feed among the readable ones, starting at 1, or 0 to take the only one. The readable
+// candidates are returned as well, to be listed when the choice is left to the user
+func ChooseFeed(candidates []FeedCandidate, pick int) (FeedCandidate, []FeedCandidate, error) {
+	var readable []FeedCandidate
+	for _, c := range candidates {
+		if c.Readable() {
+			readable = append(readable, c)
+		}
+	}
+
+	switch {
+	case len(candidates) == 0:
+		return FeedCandidate{}, nil, ErrNoFeedFound
+	case len(readable) == 0:
+		return FeedCandidate{}, nil, ErrUnsupportedFeeds
+	case pick > 0 && pick <= len(readable):
+		return readable[pick-1], readable, nil
+	case pick == 0 && len(readable) == 1:
+		return readable[0], readable, nil
+	default:
+		return FeedCandidate{}, readable, ErrPickFeed
+	}
+}
+
+// feedTypes maps the MIME types of <link rel="alternate"> feeds to their kind
+var feedTypes = map[string]string{
+	"application/rss+xml":   "rss",
+	"application/atom+xml":  "atom",
+	"application/feed+json": "json",
+}
+
+// commonFeedPaths are tried when the page does not advertise any feed
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/feed.xml", "/rss", "/atom.xml", "/index.xml"}
+
+// IsWebPage tells whether a response is an HTML page rather than a feed
+func IsWebPage(contentType string, body []byte) bool {
+	if strings.HasPrefix(strings.ToLower(contentType), "text/html") {
+		return true
+	}
+	head := strings.ToLower(string(bytes.TrimSpace(body[:min(len(body), 512)])))
+	return strings.HasPrefix(head, "<!doctype html") || strings.HasPrefix(head, "<html")
+}
+
+// DiscoverFeeds lists the feeds of a web page: the ones it links with <link rel="alternate">,
+// or else the ones found at the common feed paths of its site
+func DiscoverFeeds(ctx context.Context, pageURL string) ([]FeedCandidate, error) {
+	page, base, err := fetchPage(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []FeedCandidate
+	seen := make(map[string]bool)
+	for _, link := range parseLinks(page) {
+		kind, ok := feedTypes[strings.ToLower(strings.TrimSpace(link["type"]))]
+		if !ok || link["href"] == "" || !slices.Contains(link.rels(), "alternate") {
+			continue
+		}
+		feedURL, err := resolve(base, link["href"])
+		if err != nil || seen[feedURL] {
+			continue
+		}
+		seen[feedURL] = true
+		candidates = append(candidates, FeedCandidate{URL: feedURL, Title: strings.TrimSpace(link["title"]), Kind: kind})
+	}
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	for _, path := range commonFeedPaths {
+		feedURL := (&url.URL{Scheme: base.Scheme, Host: base.Host, Path: path}).String()
+		if kind := probeFeed(ctx, feedURL); kind != "" {
+			candidates = append(candidates, FeedCandidate{URL: feedURL, Kind: kind})
+		}
+	}
+	return candidates, ctx.Err()
+}
+
+// probeFeed returns the kind of feed served at the URL, or "" when there is none
+func probeFeed(ctx context.Context, feedURL string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	// The start of the document tells the kinds apart
+	head, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil || IsWebPage(resp.Header.Get("Content-Type"), head) {
+		return ""
+	}
+	switch text := string(head); {
+	case strings.Contains(text, "<rss"):
+		return "rss"
+	case strings.Contains(text, "<feed"):
+		return "atom"
+	case strings.Contains(text, "jsonfeed.org"):
+		return "json"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,100 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const rssBody = `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title></channel></rss>`
+
+func TestDiscoverFeeds(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+
+	got, err := DiscoverFeeds(context.Background(), srv.URL+"/blog.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FeedCandidate{
+		{URL: srv.URL + "/feed.xml", Title: "All posts", Kind: "rss"},
+		{URL: srv.URL + "/atom.xml", Title: "All posts (Atom)", Kind: "atom"},
+		{URL: "https://comments.example.org/rss?lang=en&full=1", Title: "Comments & replies", Kind: "rss"},
+		{URL: srv.URL + "/feed.json", Kind: "json"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverFeeds() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestDiscoverFeedsAtCommonPaths(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			// Like many sites, unknown paths get the home page
+			w.Header().Set("Content-Type", "text/html")
+		}
+		w.Write([]byte("<html><head><title>No feed links</title></head></html>"))
+	})
+	mux.HandleFunc("/rss.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(rssBody))
+	})
+	mux.HandleFunc("/atom.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"><title>T</title></feed>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	got, err := DiscoverFeeds(context.Background(), srv.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FeedCandidate{{URL: srv.URL + "/rss.xml", Kind: "rss"}, {URL: srv.URL + "/atom.xml", Kind: "atom"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverFeeds() = %v, want %v", got, want)
+	}
+}
+
+func TestChooseFeed(t *testing.T) {
+	rss1 := FeedCandidate{URL: "http://a.example/feed.xml", Kind: "rss"}
+	rss2 := FeedCandidate{URL: "http://a.example/comments.xml", Kind: "rss"}
+	atom := FeedCandidate{URL: "http://a.example/atom.xml", Kind: "atom"}
+	json := FeedCandidate{URL: "http://a.example/feed.json", Kind: "json"}
+
+	tests := []struct {
+		name       string
+		candidates []FeedCandidate
+		pick       int
+		want       FeedCandidate
+		err        error
+	}{
+		{"nothing found", nil, 0, FeedCandidate{}, ErrNoFeedFound},
+		{"only unreadable kinds", []FeedCandidate{atom, json}, 0, FeedCandidate{}, ErrUnsupportedFeeds},
+		{"only unreadable kinds, picked", []FeedCandidate{atom, json}, 1, FeedCandidate{}, ErrUnsupportedFeeds},
+		{"single readable one is auto-picked", []FeedCandidate{atom, rss1, json}, 0, rss1, nil},
+		{"several readable ones need a pick", []FeedCandidate{rss1, atom, rss2}, 0, FeedCandidate{}, ErrPickFeed},
+		{"pick counts readable feeds only", []FeedCandidate{rss1, atom, rss2}, 2, rss2, nil},
+		{"pick out of range", []FeedCandidate{rss1, atom, rss2}, 3, FeedCandidate{}, ErrPickFeed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, readable, err := ChooseFeed(tt.candidates, tt.pick)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ChooseFeed() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("ChooseFeed() = %v, want %v", got, tt.want)
+			}
+			for _, c := range readable {
+				if !c.Readable() {
+					t.Errorf("readable candidates include %v", c)
+				}
+			}
+		})
+	}
+}
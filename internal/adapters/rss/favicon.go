@@ -0,0 +1,58 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DiscoverIcon looks for the favicon of a website: the icon <link> of its home page first,
+// then /favicon.ico. It returns "" when the site has none
+func DiscoverIcon(ctx context.Context, siteURL string) (string, error) {
+	page, base, err := fetchPage(ctx, siteURL)
+	if err != nil {
+		return "", err
+	}
+
+	// A plain "icon" is preferred over the bigger apple-touch-icon
+	var touchIcon string
+	for _, link := range parseLinks(page) {
+		if link["href"] == "" {
+			continue
+		}
+		rels := link.rels()
+		if slices.Contains(rels, "icon") {
+			return resolve(base, link["href"])
+		}
+		if touchIcon == "" && (slices.Contains(rels, "apple-touch-icon") || slices.Contains(rels, "apple-touch-icon-precomposed")) {
+			touchIcon = link["href"]
+		}
+	}
+	if touchIcon != "" {
+		return resolve(base, touchIcon)
+	}
+
+	root := &url.URL{Scheme: base.Scheme, Host: base.Host, Path: "/favicon.ico"}
+	if iconExists(ctx, root.String()) {
+		return root.String(), nil
+	}
+	return "", nil
+}
+
+// iconExists tells whether the URL serves something other than an HTML page
+func iconExists(ctx context.Context, iconURL string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, iconURL, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	// Some sites answer every missing path with their home page
+	return resp.StatusCode == http.StatusOK && !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html")
+}
@@ -0,0 +1,81 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxPageSize limits how much of a web page is read when looking for <link> tags
+const maxPageSize = 1 << 20
+
+var (
+	linkTag   = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	attribute = regexp.MustCompile(`(?s)([a-zA-Z][a-zA-Z0-9_-]*)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+)
+
+// htmlLink is a <link> tag of a web page, attribute names are lowercase
+type htmlLink map[string]string
+
+// rels returns the lowercase tokens of the rel attribute
+func (l htmlLink) rels() []string {
+	return strings.Fields(strings.ToLower(l["rel"]))
+}
+
+// fetchPage downloads a web page and returns its body together with its final URL after redirects
+func fetchPage(ctx context.Context, pageURL string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read page: %w", err)
+	}
+	return data, resp.Request.URL, nil
+}
+
+// parseLinks returns the <link> tags of an HTML page. It is a plain scan rather than
+// a full HTML parser, which is enough for the <head> of real-world pages
+func parseLinks(page []byte) []htmlLink {
+	var links []htmlLink
+	for _, tag := range linkTag.FindAll(page, -1) {
+		link := make(htmlLink)
+		for _, m := range attribute.FindAllSubmatch(tag[len("<link"):], -1) {
+			value := strings.Trim(string(m[2]), `"'`)
+			link[strings.ToLower(string(m[1]))] = htmlUnescape(value)
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+var htmlEntities = strings.NewReplacer("&amp;", "&", "&#38;", "&", "&quot;", `"`, "&#39;", "'", "&lt;", "<", "&gt;", ">")
+
+func htmlUnescape(s string) string {
+	return htmlEntities.Replace(s)
+}
+
+// resolve turns an href of a page into an absolute URL
+func resolve(base *url.URL, href string) (string, error) {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
@@ -0,0 +1,34 @@
+package rss
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseLinks(t *testing.T) {
+	page, err := os.ReadFile("testdata/blog.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []htmlLink{
+		{"rel": "stylesheet", "href": "/style.css"},
+		{"rel": "alternate", "type": "application/rss+xml", "title": "All posts", "href": "/feed.xml"},
+		{"rel": "alternate", "type": "application/atom+xml", "title": "All posts (Atom)", "href": "/atom.xml"},
+		{"rel": "alternate", "type": "application/rss+xml", "title": "Comments & replies", "href": "https://comments.example.org/rss?lang=en&full=1"},
+		{"rel": "alternate", "type": "application/feed+json", "href": "/feed.json"},
+		{"rel": "alternate", "hreflang": "fr", "href": "/fr/"},
+		{"rel": "icon", "href": "/favicon.png"},
+	}
+	if got := parseLinks(page); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLinks() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestLinkRels(t *testing.T) {
+	link := htmlLink{"rel": "  Shortcut ICON "}
+	if got, want := link.rels(), []string{"shortcut", "icon"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rels() = %v, want %v", got, want)
+	}
+}
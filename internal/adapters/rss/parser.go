@@ -2,6 +2,7 @@ package rss
 
 import (
 	"RSSHub/internal/domain"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -13,9 +14,14 @@ import (
 
 // --- Parser ---
 
-// FetchAndParse retrieves and parses an RSS feed
-func FetchAndParse(url string) (*domain.RSSFeed, error) {
-	resp, err := http.Get(url)
+// FetchAndParse retrieves and parses an RSS feed, giving up when ctx is cancelled
+func FetchAndParse(ctx context.Context, url string) (*domain.RSSFeed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch RSS: %w", err)
 	}
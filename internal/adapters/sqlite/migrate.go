@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"RSSHub/internal/domain"
+	"RSSHub/pkg/logger"
+)
+
+var _ domain.Migrator = (*SQLiteRepository)(nil)
+
+// The version table mirrors the Postgres one, see db.PostgresRepository
+func (r *SQLiteRepository) ensureVersionTable(ctx context.Context) error {
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`
+	_, err := r.db.ExecContext(ctx, query)
+	return err
+}
+
+func (r *SQLiteRepository) schemaVersion(ctx context.Context) (int64, bool, error) {
+	var version int64
+	var dirty bool
+	err := r.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// MigrationStatus compares the applied schema version with the given migrations
+func (r *SQLiteRepository) MigrationStatus(ctx context.Context, migrations []domain.Migration) (domain.MigrationStatus, error) {
+	if err := r.ensureVersionTable(ctx); err != nil {
+		return domain.MigrationStatus{}, fmt.Errorf("failed to create version table: %w", err)
+	}
+
+	current, dirty, err := r.schemaVersion(ctx)
+	if err != nil {
+		return domain.MigrationStatus{}, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	status := domain.MigrationStatus{Current: current, Dirty: dirty}
+	for _, m := range migrations {
+		if m.Version <= current {
+			status.Applied = append(status.Applied, m)
+		} else {
+			status.Pending = append(status.Pending, m)
+		}
+		status.Latest = m.Version
+	}
+	return status, nil
+}
+
+// MigrateUp applies every pending migration, each one in its own transaction
+func (r *SQLiteRepository) MigrateUp(ctx context.Context, migrations []domain.Migration) ([]domain.Migration, error) {
+	status, err := r.MigrationStatus(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+	if status.Dirty {
+		return nil, fmt.Errorf("schema version %d is dirty, fix the database by hand first", status.Current)
+	}
+
+	var applied []domain.Migration
+	for _, m := range status.Pending {
+		if err := r.runMigration(ctx, m.Up, m.Version); err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		logger.Debug("Migration applied", "version", m.Version, "name", m.Name)
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// MigrateDown rolls back the last steps applied migrations
+func (r *SQLiteRepository) MigrateDown(ctx context.Context, migrations []domain.Migration, steps int) ([]domain.Migration, error) {
+	status, err := r.MigrationStatus(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+	if status.Dirty {
+		return nil, fmt.Errorf("schema version %d is dirty, fix the database by hand first", status.Current)
+	}
+
+	var reverted []domain.Migration
+	for i := len(status.Applied) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := status.Applied[i]
+		var previous int64
+		if i > 0 {
+			previous = status.Applied[i-1].Version
+		}
+		if err := r.runMigration(ctx, m.Down, previous); err != nil {
+			return reverted, fmt.Errorf("rollback of %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		logger.Debug("Migration rolled back", "version", m.Version, "name", m.Name)
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+// runMigration executes a script and records the resulting version atomically.
+// Version 0 means no migration is applied
+func (r *SQLiteRepository) runMigration(ctx context.Context, script string, version int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if strings.TrimSpace(script) != "" {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES (?, FALSE)`, version); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
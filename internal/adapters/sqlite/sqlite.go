@@ -0,0 +1,858 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"RSSHub/internal/domain"
+	"RSSHub/internal/domain/utils"
+	"RSSHub/pkg/logger"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var _ domain.Repository = (*SQLiteRepository)(nil)
+
+// driverName is the sqlite3 driver with the functions the queries below need
+const driverName = "sqlite3_rsshub"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("rank_match", rankMatch, true)
+		},
+	})
+}
+
+// SQLiteRepository keeps everything in a single SQLite file, for single-user setups.
+// All timestamps are stored in UTC so that they compare correctly as text
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository opens (and creates if needed) the database file at path
+func NewSQLiteRepository(ctx context.Context, path string) (*SQLiteRepository, error) {
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL", path)
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db: %w", err)
+	}
+
+	// SQLite allows one writer at a time, a single connection avoids "database is locked" errors
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open db file %s: %w", path, err)
+	}
+
+	logger.Debug("Succefully opened SQLite database!", "path", path)
+
+	return &SQLiteRepository{db: db}, nil
+}
+
+// Close DB connection
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// -------------------------------------------------------------Feeds--------------------------------------------------------------------
+
+// AddFeed stores a new feed with its tags, a feed with the same name is left as it is
+func (r *SQLiteRepository) AddFeed(ctx context.Context, feed domain.Feed) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO feeds (
+			id, name, url, created_at, updated_at, folder, fetch_interval_seconds,
+			title, site_url, description, language, image_url, icon_url, icon_checked_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (name) DO NOTHING;
+	`
+	m := feed.Meta
+	var iconCheckedAt sql.NullTime
+	if !m.IconCheckedAt.IsZero() {
+		iconCheckedAt = sql.NullTime{Time: m.IconCheckedAt.UTC(), Valid: true}
+	}
+	id := utils.NewUUID()
+	result, err := tx.ExecContext(ctx, query,
+		id, feed.Name, feed.URL, feed.CreatedAt.UTC(), feed.UpdatedAt.UTC(), feed.Folder, int64(feed.Interval/time.Second),
+		m.Title, m.SiteURL, m.Description, m.Language, m.ImageURL, m.IconURL, iconCheckedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if err := insertTags(ctx, tx, id, feed.Tags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// feedColumns are read by scanFeed, the tags come as a comma-separated list
+const feedColumns = `
+	f.id, f.name, f.url, f.created_at, f.updated_at, f.folder, f.fetch_interval_seconds,
+	(SELECT group_concat(t.tag) FROM feed_tags t WHERE t.feed_id = f.id),
+	f.title, f.site_url, f.description, f.language, f.image_url, f.icon_url, f.icon_checked_at
+`
+
+// scanFeed reads the feedColumns and then the extra columns selected after them
+func scanFeed(row interface{ Scan(...any) error }, extra ...any) (domain.Feed, error) {
+	var f domain.Feed
+	var interval int64
+	var tags sql.NullString
+	var iconCheckedAt sql.NullTime
+	m := &f.Meta
+	dest := []any{
+		&f.ID, &f.Name, &f.URL, &f.CreatedAt, &f.UpdatedAt, &f.Folder, &interval, &tags,
+		&m.Title, &m.SiteURL, &m.Description, &m.Language, &m.ImageURL, &m.IconURL, &iconCheckedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return domain.Feed{}, err
+	}
+	f.Interval = time.Duration(interval) * time.Second
+	m.IconCheckedAt = iconCheckedAt.Time
+	if tags.String != "" {
+		f.Tags = strings.Split(tags.String, ",")
+		sort.Strings(f.Tags)
+	}
+	return f, nil
+}
+
+func (r *SQLiteRepository) ListFeedByName(ctx context.Context, feedName string) (domain.Feed, error) {
+	query := `SELECT ` + feedColumns + ` FROM feeds f WHERE f.name = ?`
+	return scanFeed(r.db.QueryRowContext(ctx, query, feedName))
+}
+
+func (r *SQLiteRepository) ListFeeds(ctx context.Context, filter domain.FeedFilter, limit int) ([]domain.Feed, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("--num parameter cannot be negative")
+	}
+
+	// A negative LIMIT means no limit in SQLite
+	if limit == 0 {
+		limit = -1
+	}
+	query := `
+		SELECT ` + feedColumns + `
+		FROM feeds f
+		WHERE (? = '' OR f.folder = ?)
+			AND (? = '' OR EXISTS (SELECT 1 FROM feed_tags t WHERE t.feed_id = f.id AND t.tag = ?))
+		ORDER BY f.created_at DESC
+		LIMIT ?
+	`
+	return r.queryFeeds(ctx, query, filter.Folder, filter.Folder, filter.Tag, filter.Tag, limit)
+}
+
+// ListDueFeeds returns the feeds not fetched since the given moment, least recently fetched first
+func (r *SQLiteRepository) ListDueFeeds(ctx context.Context, fetchedBefore time.Time) ([]domain.Feed, error) {
+	query := `
+		SELECT ` + feedColumns + `
+		FROM feeds f
+		WHERE f.updated_at <= ?
+		ORDER BY f.updated_at ASC
+	`
+	return r.queryFeeds(ctx, query, fetchedBefore.UTC())
+}
+
+func (r *SQLiteRepository) queryFeeds(ctx context.Context, query string, args ...any) ([]domain.Feed, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feeds []domain.Feed
+	for rows.Next() {
+		f, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// TagFeed adds the tags the feed does not carry yet
+func (r *SQLiteRepository) TagFeed(ctx context.Context, feedName string, tags []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	feedID, err := feedIDByName(ctx, tx, feedName)
+	if err != nil {
+		return err
+	}
+	if err := insertTags(ctx, tx, feedID, tags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func feedIDByName(ctx context.Context, tx *sql.Tx, feedName string) (string, error) {
+	var feedID string
+	err := tx.QueryRowContext(ctx, `SELECT id FROM feeds WHERE name = ?`, feedName).Scan(&feedID)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("The feed is not present in db!")
+	}
+	return feedID, err
+}
+
+func insertTags(ctx context.Context, tx *sql.Tx, feedID string, tags []string) error {
+	for _, tag := range tags {
+		_, err := tx.ExecContext(ctx, `INSERT INTO feed_tags (feed_id, tag) VALUES (?, ?) ON CONFLICT DO NOTHING`, feedID, tag)
+		if err != nil {
+			return fmt.Errorf("failed to tag feed: %w", err)
+		}
+	}
+	return nil
+}
+
+// UntagFeed removes the tags from the feed, tags it does not carry are ignored
+func (r *SQLiteRepository) UntagFeed(ctx context.Context, feedName string, tags []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	feedID, err := feedIDByName(ctx, tx, feedName)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM feed_tags WHERE feed_id = ? AND tag = ?`, feedID, tag); err != nil {
+			return fmt.Errorf("failed to untag feed: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteRepository) UpdateFeedTimestamp(ctx context.Context, feedID string, updatedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE feeds SET updated_at = ? WHERE id = ?`, updatedAt.UTC(), feedID)
+	return err
+}
+
+// UpdateFeed replaces the name, URL, folder and fetch interval of the named feed.
+// Its id stays the same, so the articles and their read state are kept
+func (r *SQLiteRepository) UpdateFeed(ctx context.Context, name string, feed domain.Feed) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	feedID, err := feedIDByName(ctx, tx, name)
+	if err != nil {
+		return err
+	}
+
+	if feed.Name != name {
+		var taken bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM feeds WHERE name = ?)`, feed.Name).Scan(&taken); err != nil {
+			return err
+		}
+		if taken {
+			return fmt.Errorf("A feed named %q is already present in db!", feed.Name)
+		}
+	}
+
+	query := `
+		UPDATE feeds
+		SET name = ?, url = ?, folder = ?, fetch_interval_seconds = ?
+		WHERE id = ?
+	`
+	if _, err := tx.ExecContext(ctx, query, feed.Name, feed.URL, feed.Folder, int64(feed.Interval/time.Second), feedID); err != nil {
+		return fmt.Errorf("failed to update feed: %w", err)
+	}
+	return tx.Commit()
+}
+
+// UpdateFeedMeta stores what the feed said about itself on the last fetch
+func (r *SQLiteRepository) UpdateFeedMeta(ctx context.Context, feedID string, meta domain.FeedMeta) error {
+	var iconCheckedAt sql.NullTime
+	if !meta.IconCheckedAt.IsZero() {
+		iconCheckedAt = sql.NullTime{Time: meta.IconCheckedAt.UTC(), Valid: true}
+	}
+	query := `
+		UPDATE feeds
+		SET title = ?, site_url = ?, description = ?, language = ?, image_url = ?, icon_url = ?, icon_checked_at = ?
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		meta.Title, meta.SiteURL, meta.Description, meta.Language, meta.ImageURL, meta.IconURL, iconCheckedAt, feedID,
+	)
+	return err
+}
+
+func (r *SQLiteRepository) DeleteFeed(ctx context.Context, name string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM feeds WHERE name = ?`, name)
+	if err != nil {
+		logger.Error("Error deleting feed", "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error getting rows affected after delete", "error", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("The feed is not present in db!")
+	}
+
+	return nil
+}
+
+// -------------------------------------------------------------Articles--------------------------------------------------------------------
+
+// AddArticle inserts a new article or refreshes the stored one with the same link when its content changed
+func (r *SQLiteRepository) AddArticle(ctx context.Context, article domain.Article) (domain.ArticleStatus, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.ArticleSkipped, err
+	}
+	defer tx.Rollback()
+
+	status, err := upsertArticle(ctx, tx, article.FeedID, article)
+	if err != nil {
+		return domain.ArticleSkipped, err
+	}
+	return status, tx.Commit()
+}
+
+// IngestFeed stores all articles of a fetched feed and updates its timestamp in one transaction
+func (r *SQLiteRepository) IngestFeed(ctx context.Context, feedID string, articles []domain.Article, fetchedAt time.Time) (domain.IngestStats, error) {
+	var stats domain.IngestStats
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, a := range articles {
+		status, err := upsertArticle(ctx, tx, feedID, a)
+		if err != nil {
+			return domain.IngestStats{}, fmt.Errorf("failed to insert article %q: %w", a.Link, err)
+		}
+		stats.Add(status)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE feeds SET updated_at = ? WHERE id = ?`, fetchedAt.UTC(), feedID); err != nil {
+		return domain.IngestStats{}, fmt.Errorf("failed to update feed timestamp: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.IngestStats{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return stats, nil
+}
+
+// upsertArticle deduplicates by link the same way the Postgres ON CONFLICT clause does.
+// Articles removed by the retention rules stay removed, even when the feed rewrote their link
+func upsertArticle(ctx context.Context, tx *sql.Tx, feedID string, a domain.Article) (domain.ArticleStatus, error) {
+	var pruned bool
+	query := `SELECT EXISTS (SELECT 1 FROM pruned_articles WHERE link = ? OR (? <> '' AND guid = ?))`
+	err := tx.QueryRowContext(ctx, query, a.Link, a.GUID, a.GUID).Scan(&pruned)
+	if err != nil {
+		return domain.ArticleSkipped, err
+	}
+	if pruned {
+		return domain.ArticleSkipped, nil
+	}
+
+	var title, content string
+	var description sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT title, description, content FROM articles WHERE link = ?`, a.Link).Scan(&title, &description, &content)
+	if err == sql.ErrNoRows {
+		query := `
+			INSERT INTO articles (id, created_at, updated_at, title, link, guid, description, content, published_at, feed_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`
+		_, err := tx.ExecContext(ctx, query,
+			utils.NewUUID(), a.CreatedAt.UTC(), a.UpdatedAt.UTC(), a.Title, a.Link, a.GUID, a.Description, a.Content, a.PublishedAt.UTC(), feedID,
+		)
+		if err != nil {
+			return domain.ArticleSkipped, err
+		}
+		return domain.ArticleNew, nil
+	} else if err != nil {
+		return domain.ArticleSkipped, err
+	}
+
+	if title == a.Title && description.String == a.Description && content == a.Content {
+		return domain.ArticleSkipped, nil
+	}
+
+	query = `UPDATE articles SET title = ?, description = ?, content = ?, updated_at = ? WHERE link = ?`
+	if _, err := tx.ExecContext(ctx, query, a.Title, a.Description, a.Content, a.UpdatedAt.UTC(), a.Link); err != nil {
+		return domain.ArticleSkipped, err
+	}
+	return domain.ArticleUpdated, nil
+}
+
+// ListArticles returns the N latest articles for a feed
+func (r *SQLiteRepository) ListArticles(ctx context.Context, feedName string, num int) ([]domain.Article, error) {
+	query := `
+		SELECT a.id, a.created_at, a.updated_at, a.title, a.link, a.guid, a.description, a.content, a.published_at, a.feed_id, a.read, a.starred
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		WHERE f.name = ?
+		ORDER BY a.published_at DESC
+		LIMIT ?;
+	`
+	return r.queryArticles(ctx, query, feedName, num)
+}
+
+// ListArticlesByFeed returns the N most recent articles for a feed that pass the filter
+func (r *SQLiteRepository) ListArticlesByFeed(ctx context.Context, feedID string, filter domain.ArticleFilter, limit int) ([]domain.Article, error) {
+	query := `
+		SELECT id, created_at, updated_at, title, link, guid, description, content, published_at, feed_id, read, starred
+		FROM articles
+		WHERE feed_id = ?
+			AND (NOT ? OR NOT read)
+			AND (NOT ? OR starred)
+		ORDER BY published_at DESC
+		LIMIT ?;
+	`
+	return r.queryArticles(ctx, query, feedID, filter.Unread, filter.Starred, limit)
+}
+
+func (r *SQLiteRepository) SetArticleRead(ctx context.Context, id string, read bool) error {
+	return r.setArticleFlag(ctx, `UPDATE articles SET read = ? WHERE id = ?`, read, id)
+}
+
+func (r *SQLiteRepository) SetArticleStarred(ctx context.Context, id string, starred bool) error {
+	return r.setArticleFlag(ctx, `UPDATE articles SET starred = ? WHERE id = ?`, starred, id)
+}
+
+func (r *SQLiteRepository) setArticleFlag(ctx context.Context, query string, value bool, id string) error {
+	result, err := r.db.ExecContext(ctx, query, value, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("The article is not present in db!")
+	}
+	return nil
+}
+
+// MarkFeedRead marks every article of a feed as read and returns how many were unread
+func (r *SQLiteRepository) MarkFeedRead(ctx context.Context, feedID string) (int, error) {
+	result, err := r.db.ExecContext(ctx, `UPDATE articles SET read = TRUE WHERE feed_id = ? AND NOT read`, feedID)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return int(rowsAffected), err
+}
+
+// CountUnreadArticles returns the number of unread articles by feed id, feeds without any are left out
+func (r *SQLiteRepository) CountUnreadArticles(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT feed_id, COUNT(*) FROM articles WHERE NOT read GROUP BY feed_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var feedID string
+		var count int
+		if err := rows.Scan(&feedID, &count); err != nil {
+			return nil, err
+		}
+		counts[feedID] = count
+	}
+	return counts, rows.Err()
+}
+
+func (r *SQLiteRepository) queryArticles(ctx context.Context, query string, args ...any) ([]domain.Article, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var articles []domain.Article
+	for rows.Next() {
+		a, err := scanArticle(rows)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
+
+// scanArticle reads the article columns in the order the article queries select them, followed by extra
+func scanArticle(rows *sql.Rows, extra ...any) (domain.Article, error) {
+	var a domain.Article
+	var description sql.NullString
+	var publishedAt sql.NullTime
+	dest := append([]any{&a.ID, &a.CreatedAt, &a.UpdatedAt, &a.Title, &a.Link, &a.GUID, &description, &a.Content, &publishedAt, &a.FeedID, &a.Read, &a.Starred}, extra...)
+	if err := rows.Scan(dest...); err != nil {
+		return domain.Article{}, err
+	}
+	a.Description = description.String
+	a.PublishedAt = publishedAt.Time
+	return a, nil
+}
+
+// Timeline merges the articles of several feeds, newest first, one page at a time.
+// Pages are cut with a keyset on (published_at, id) so that new articles do not shift them
+func (r *SQLiteRepository) Timeline(ctx context.Context, q domain.TimelineQuery) ([]domain.TimelineEntry, error) {
+	var sb strings.Builder
+	sb.WriteString(`
+		SELECT a.id, a.created_at, a.updated_at, a.title, a.link, a.guid, a.description, a.content, a.published_at, a.feed_id, a.read, a.starred,
+			f.name
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		WHERE (NOT ? OR NOT a.read)
+			AND (NOT ? OR a.starred)`)
+	args := []any{q.Filter.Unread, q.Filter.Starred}
+
+	if len(q.FeedNames) > 0 {
+		sb.WriteString(` AND f.name IN (?` + strings.Repeat(", ?", len(q.FeedNames)-1) + `)`)
+		for _, name := range q.FeedNames {
+			args = append(args, name)
+		}
+	}
+	if q.Feeds.Folder != "" {
+		sb.WriteString(` AND f.folder = ?`)
+		args = append(args, q.Feeds.Folder)
+	}
+	if q.Feeds.Tag != "" {
+		sb.WriteString(` AND EXISTS (SELECT 1 FROM feed_tags t WHERE t.feed_id = f.id AND t.tag = ?)`)
+		args = append(args, q.Feeds.Tag)
+	}
+	if !q.Since.IsZero() {
+		sb.WriteString(` AND a.published_at >= ?`)
+		args = append(args, q.Since.UTC())
+	}
+	if !q.Until.IsZero() {
+		sb.WriteString(` AND a.published_at < ?`)
+		args = append(args, q.Until.UTC())
+	}
+	if q.After != nil {
+		sb.WriteString(` AND (a.published_at, a.id) < (?, ?)`)
+		args = append(args, q.After.PublishedAt.UTC(), q.After.ID)
+	}
+	sb.WriteString(`
+		ORDER BY a.published_at DESC, a.id DESC
+		LIMIT ?`)
+	args = append(args, q.Limit)
+
+	rows, err := r.db.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []domain.TimelineEntry
+	for rows.Next() {
+		var e domain.TimelineEntry
+		e.Article, err = scanArticle(rows, &e.FeedName)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Weights of the title, description and content columns, the same as the default ones of ts_rank
+var searchWeights = []float64{1.0, 0.4, 0.2}
+
+// SearchArticles looks the query up in the articles_fts index. FTS4 has no ranking function,
+// so every match is scored from matchinfo() by rank_match, registered with the driver
+func (r *SQLiteRepository) SearchArticles(ctx context.Context, q domain.SearchQuery) ([]domain.SearchResult, error) {
+	match := matchExpression(q.Text)
+	if match == "" {
+		return nil, nil
+	}
+
+	var since sql.NullTime
+	if !q.Since.IsZero() {
+		since = sql.NullTime{Time: q.Since.UTC(), Valid: true}
+	}
+
+	query := `
+		SELECT a.id, a.created_at, a.updated_at, a.title, a.link, a.guid, a.description, a.content, a.published_at, a.feed_id, a.read, a.starred,
+			f.name,
+			rank_match(matchinfo(articles_fts, 'pcx')) AS rank,
+			snippet(articles_fts, ?, ?, '...', -1, 30)
+		FROM articles_fts
+		JOIN articles a ON a.rowid = articles_fts.docid
+		JOIN feeds f ON a.feed_id = f.id
+		WHERE articles_fts MATCH ?
+			AND (? = '' OR f.name = ?)
+			AND (? IS NULL OR a.published_at >= ?)
+		ORDER BY rank DESC, a.published_at DESC
+		LIMIT ? OFFSET ?
+	`
+	// A negative LIMIT means no limit in SQLite
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := r.db.QueryContext(ctx, query,
+		domain.HighlightStart, domain.HighlightStop, match, q.FeedName, q.FeedName, since, since, limit, q.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.SearchResult
+	for rows.Next() {
+		var res domain.SearchResult
+		res.Article, err = scanArticle(rows, &res.FeedName, &res.Rank, &res.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		res.Snippet = utils.StripHTML(res.Snippet)
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// matchExpression quotes every word of the user query, so that all of them must match
+// and characters with a meaning in the FTS query syntax are taken literally
+func matchExpression(text string) string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ReplaceAll(text, `"`, " ")) {
+		terms = append(terms, `"`+word+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// rankMatch scores a row from matchinfo 'pcx': for every phrase and column it adds the weighted
+// share of all the phrase hits that fall in this row
+func rankMatch(info []byte) float64 {
+	if len(info) < 8 {
+		return 0
+	}
+	value := func(i int) float64 { return float64(binary.NativeEndian.Uint32(info[i*4:])) }
+
+	phrases, columns := int(value(0)), int(value(1))
+	if len(info) < (2+3*phrases*columns)*4 {
+		return 0
+	}
+
+	rank := 0.0
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < columns && c < len(searchWeights); c++ {
+			base := 2 + 3*(p*columns+c)
+			hitsInRow, hitsInAll := value(base), value(base+1)
+			if hitsInAll > 0 {
+				rank += searchWeights[c] * hitsInRow / hitsInAll
+			}
+		}
+	}
+	return rank
+}
+
+// -------------------------------------------------------------Fetch jobs--------------------------------------------------------------------
+
+// EnqueueFetchJob queues a feed for fetching. If the feed is already queued its priority is raised when needed
+func (r *SQLiteRepository) EnqueueFetchJob(ctx context.Context, feedID string, priority int) error {
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO fetch_jobs (feed_id, priority, created_at, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (feed_id) WHERE state IN ('queued', 'running')
+		DO UPDATE SET priority = MAX(priority, excluded.priority), updated_at = excluded.updated_at;
+	`
+	_, err := r.db.ExecContext(ctx, query, feedID, priority, now, now)
+	return err
+}
+
+// ClaimFetchJob leases the most urgent queued job, or a running job whose lease has expired
+func (r *SQLiteRepository) ClaimFetchJob(ctx context.Context, lease time.Duration) (domain.FetchJob, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.FetchJob{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+
+	// Jobs that ran out of their lease on the last allowed attempt are given up
+	query := `
+		UPDATE fetch_jobs
+		SET state = 'failed', leased_until = NULL, last_error = ?, updated_at = ?
+		WHERE state = 'running' AND leased_until < ? AND attempts >= ?
+	`
+	if _, err := tx.ExecContext(ctx, query, domain.AbandonedJobError, now, now, domain.MaxJobAttempts); err != nil {
+		return domain.FetchJob{}, fmt.Errorf("failed to give up abandoned jobs: %w", err)
+	}
+
+	var id int64
+	query = `
+		SELECT id FROM fetch_jobs
+		WHERE state = 'queued' OR (state = 'running' AND leased_until < ?)
+		ORDER BY priority DESC, created_at
+		LIMIT 1
+	`
+	err = tx.QueryRowContext(ctx, query, now).Scan(&id)
+	if err == sql.ErrNoRows {
+		// The jobs given up above must stay failed
+		if err := tx.Commit(); err != nil {
+			return domain.FetchJob{}, err
+		}
+		return domain.FetchJob{}, domain.ErrNoJobs
+	} else if err != nil {
+		return domain.FetchJob{}, err
+	}
+
+	query = `
+		UPDATE fetch_jobs
+		SET state = 'running', attempts = attempts + 1, leased_until = ?, updated_at = ?
+		WHERE id = ?
+	`
+	if _, err := tx.ExecContext(ctx, query, now.Add(lease), now, id); err != nil {
+		return domain.FetchJob{}, err
+	}
+
+	var job domain.FetchJob
+	query = `
+		SELECT ` + feedColumns + `,
+			j.id, j.state, j.priority, j.attempts, j.leased_until, j.created_at, j.updated_at
+		FROM fetch_jobs j
+		JOIN feeds f ON f.id = j.feed_id
+		WHERE j.id = ?
+	`
+	job.Feed, err = scanFeed(tx.QueryRowContext(ctx, query, id),
+		&job.ID, &job.State, &job.Priority, &job.Attempts, &job.LeasedUntil, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		return domain.FetchJob{}, err
+	}
+	return job, tx.Commit()
+}
+
+func (r *SQLiteRepository) CompleteFetchJob(ctx context.Context, id int64) error {
+	query := `UPDATE fetch_jobs SET state = 'done', leased_until = NULL, updated_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, time.Now().UTC(), id)
+	return err
+}
+
+func (r *SQLiteRepository) FailFetchJob(ctx context.Context, id int64, reason string) error {
+	query := `UPDATE fetch_jobs SET state = 'failed', leased_until = NULL, last_error = ?, updated_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, reason, time.Now().UTC(), id)
+	return err
+}
+
+// ReleaseFetchJob puts an unfinished job back in the queue so it is picked up without waiting for its lease
+func (r *SQLiteRepository) ReleaseFetchJob(ctx context.Context, id int64) error {
+	query := `UPDATE fetch_jobs SET state = 'queued', leased_until = NULL, updated_at = ? WHERE id = ? AND state = 'running'`
+	_, err := r.db.ExecContext(ctx, query, time.Now().UTC(), id)
+	return err
+}
+
+func (r *SQLiteRepository) CountQueuedFetchJobs(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM fetch_jobs WHERE state = 'queued'`).Scan(&count)
+	return count, err
+}
+
+// PurgeFetchJobs deletes done and failed jobs that finished before the given moment
+func (r *SQLiteRepository) PurgeFetchJobs(ctx context.Context, finishedBefore time.Time) error {
+	query := `DELETE FROM fetch_jobs WHERE state IN ('done', 'failed') AND updated_at < ?`
+	_, err := r.db.ExecContext(ctx, query, finishedBefore.UTC())
+	return err
+}
+
+// -------------------------------------------------------------Share--------------------------------------------------------------------
+
+func (r *SQLiteRepository) FetchCliInterval(ctx context.Context) (string, error) {
+	var interval string
+	err := r.db.QueryRowContext(ctx, `SELECT interval FROM share WHERE id = 1 AND interval <> '' AND workers_num > 0`).Scan(&interval)
+	if err == sql.ErrNoRows {
+		return "", err
+	}
+	return interval, nil
+}
+
+func (r *SQLiteRepository) SetInterval(ctx context.Context, interval string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE share SET interval = ? WHERE id = 1`, interval)
+	return err
+}
+
+func (r *SQLiteRepository) SetDefaultCliIntervalAndWorkersNum(ctx context.Context, interval string, workersNum int) error {
+	query := `
+		INSERT INTO share (id, interval, workers_num)
+		VALUES (1, ?, ?)
+		ON CONFLICT (id)
+		DO UPDATE SET interval = excluded.interval, workers_num = excluded.workers_num;
+	`
+	_, err := r.db.ExecContext(ctx, query, interval, workersNum)
+	return err
+}
+
+func (r *SQLiteRepository) SetWorkers(ctx context.Context, workersNum int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE share SET workers_num = ? WHERE id = 1`, workersNum)
+	return err
+}
+
+func (r *SQLiteRepository) FetchWorkersNumber(ctx context.Context) (int, error) {
+	var workersNum int
+	err := r.db.QueryRowContext(ctx, `SELECT workers_num FROM share WHERE id = 1 AND interval <> '' AND workers_num > 0`).Scan(&workersNum)
+	if err == sql.ErrNoRows {
+		return 0, err
+	}
+	return workersNum, nil
+}
+
+func (r *SQLiteRepository) SetPauseState(ctx context.Context, state domain.PauseState) error {
+	var until sql.NullTime
+	if state.Paused && !state.Until.IsZero() {
+		until = sql.NullTime{Time: state.Until.UTC(), Valid: true}
+	}
+
+	// Pausing works before the first fetch too, the row it creates has no
+	// interval and worker count yet and FetchCliInterval keeps reporting sql.ErrNoRows
+	query := `
+		INSERT INTO share (id, interval, workers_num, paused, paused_until)
+		VALUES (1, '', 0, ?, ?)
+		ON CONFLICT (id)
+		DO UPDATE SET paused = excluded.paused, paused_until = excluded.paused_until;
+	`
+	_, err := r.db.ExecContext(ctx, query, state.Paused, until)
+	return err
+}
+
+func (r *SQLiteRepository) FetchPauseState(ctx context.Context) (domain.PauseState, error) {
+	var state domain.PauseState
+	var until sql.NullTime
+	err := r.db.QueryRowContext(ctx, `SELECT paused, paused_until FROM share WHERE id = 1`).Scan(&state.Paused, &until)
+	if err != nil {
+		return domain.PauseState{}, err
+	}
+	if until.Valid {
+		state.Until = until.Time
+	}
+	return state, nil
+}
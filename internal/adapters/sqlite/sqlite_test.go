@@ -0,0 +1,50 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"RSSHub/internal/adapters/repotest"
+	"RSSHub/internal/adapters/sqlite"
+	"RSSHub/internal/domain"
+	"RSSHub/migrations"
+)
+
+// newTestRepository opens a migrated SQLite file in a temporary directory
+func newTestRepository(tb testing.TB) *sqlite.SQLiteRepository {
+	tb.Helper()
+	allMigrations, err := migrations.LoadSQLite()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	ctx := context.Background()
+	repo, err := sqlite.NewSQLiteRepository(ctx, filepath.Join(tb.TempDir(), "rsshub.db"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := repo.MigrateUp(ctx, allMigrations); err != nil {
+		repo.Close()
+		tb.Fatal(err)
+	}
+	return repo
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	repotest.TestRepository(t, func(t *testing.T) domain.Repository {
+		return newTestRepository(t)
+	})
+}
+
+func BenchmarkIngestFeed(b *testing.B) {
+	repo := newTestRepository(b)
+	defer repo.Close()
+	repotest.BenchmarkIngestFeed(b, repo)
+}
+
+func BenchmarkAddArticleLoop(b *testing.B) {
+	repo := newTestRepository(b)
+	defer repo.Close()
+	repotest.BenchmarkAddArticleLoop(b, repo)
+}
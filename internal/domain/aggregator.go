@@ -7,13 +7,37 @@ import (
 
 type Aggregator interface {
 	Start(ctx context.Context) error
-	Stop()
-	Worker(ctx context.Context, id int)
+	Stop(drainTimeout time.Duration) []Feed
+	Worker(ctx context.Context, id int, quit <-chan struct{})
 	GetCurrentInterval() time.Duration
-	SetCurrentInterval(interval time.Duration)
-	RestartTicker()
 	SetInterval(d time.Duration)
 	GetWorkersNum() int
-	SetWorkersNum(workersNum int)
-	UpdateWorkers(ctx context.Context, oldWorkersNum int, workersNum int)
+	UpdateWorkers(workersNum int) error
+	Pause(until time.Time)
+	Resume()
+	IsPaused() bool
+	PausedUntil() time.Time
+	Enqueue(ctx context.Context, feed Feed) error
+	Stats(ctx context.Context) AggregatorStats
+	RunOnce(ctx context.Context, feeds []Feed) ([]FeedResult, error)
+}
+
+// FeedResult is the outcome of fetching one feed
+type FeedResult struct {
+	Feed  Feed
+	Stats IngestStats
+	Err   error
+}
+
+// AggregatorStats is a snapshot of what the aggregator is doing right now
+type AggregatorStats struct {
+	Interval        time.Duration
+	Workers         int
+	BusyWorkers     int
+	QueueDepth      int
+	Paused          bool
+	PausedUntil     time.Time
+	LastTick        time.Time
+	FetchedLastHour int
+	FailedLastHour  int
 }
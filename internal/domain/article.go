@@ -3,12 +3,52 @@ package domain
 import "time"
 
 type Article struct {
-	ID          string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Link        string
+	ID        string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Title     string
+	Link      string
+	// GUID is the <guid> of the item, empty when the feed gives none
+	GUID        string
 	Description string
+	// Content is the full text from content:encoded, empty when the feed has only a description
+	Content     string
 	PublishedAt time.Time
 	FeedID      string
+	Read        bool
+	// Starred articles are kept by the retention rules
+	Starred bool
+}
+
+// ArticleFilter narrows a list of articles, false fields do not filter
+type ArticleFilter struct {
+	Unread  bool
+	Starred bool
+}
+
+// ArticleStatus tells what saving an article did to the stored copy
+type ArticleStatus int
+
+const (
+	ArticleNew ArticleStatus = iota
+	ArticleUpdated
+	ArticleSkipped
+)
+
+// IngestStats counts what happened to the articles of a fetched feed
+type IngestStats struct {
+	New     int
+	Updated int
+	Skipped int
+}
+
+func (s *IngestStats) Add(status ArticleStatus) {
+	switch status {
+	case ArticleNew:
+		s.New++
+	case ArticleUpdated:
+		s.Updated++
+	default:
+		s.Skipped++
+	}
 }
@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 type Feed struct {
 	ID        string
@@ -8,4 +11,54 @@ type Feed struct {
 	URL       string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// Folder is empty for feeds outside any folder
+	Folder string
+	// Tags are lowercase and sorted
+	Tags []string
+	// Interval overrides the global fetch interval when set
+	Interval time.Duration
+	Meta     FeedMeta
+}
+
+// FeedMeta is what the feed says about itself, refreshed on every fetch
+type FeedMeta struct {
+	Title       string
+	SiteURL     string
+	Description string
+	Language    string
+	// ImageURL is the channel's <image>, IconURL the favicon of the site
+	ImageURL string
+	IconURL  string
+	// IconCheckedAt is when the favicon was last looked for, zero when never
+	IconCheckedAt time.Time
+}
+
+// Due tells whether the feed should be fetched again, given the global fetch interval
+func (f Feed) Due(now time.Time, defaultInterval time.Duration) bool {
+	interval := defaultInterval
+	if f.Interval > 0 {
+		interval = f.Interval
+	}
+	return !f.UpdatedAt.Add(interval).After(now)
+}
+
+// FeedFilter narrows a list of feeds, empty fields do not filter
+type FeedFilter struct {
+	Folder string
+	Tag    string
+}
+
+func (f FeedFilter) IsZero() bool {
+	return f.Folder == "" && f.Tag == ""
+}
+
+// Match tells whether the feed passes the filter
+func (f FeedFilter) Match(feed Feed) bool {
+	if f.Folder != "" && feed.Folder != f.Folder {
+		return false
+	}
+	if f.Tag != "" && !slices.Contains(feed.Tags, f.Tag) {
+		return false
+	}
+	return true
 }
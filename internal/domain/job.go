@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// JobState is the lifecycle state of a fetch job
+type JobState string
+
+const (
+	JobQueued  JobState = "queued"
+	JobRunning JobState = "running"
+	JobDone    JobState = "done"
+	JobFailed  JobState = "failed"
+)
+
+// Priorities of fetch jobs, higher ones are picked first
+const (
+	PriorityNormal = 0
+	PriorityHigh   = 10
+)
+
+// MaxJobAttempts is how many times a job may be claimed. A job still running when its last lease
+// runs out is marked failed instead of being claimed again, so that a feed that crashes or hangs
+// the worker is not retried forever
+const MaxJobAttempts = 3
+
+// AbandonedJobError is the last error of the jobs failed after MaxJobAttempts
+const AbandonedJobError = "the lease ran out on every attempt"
+
+// ErrNoJobs is returned when there is no fetch job ready to be picked
+var ErrNoJobs = errors.New("no fetch jobs available")
+
+// FetchJob is a durable request to fetch one feed
+type FetchJob struct {
+	ID          int64
+	Feed        Feed
+	State       JobState
+	Priority    int
+	Attempts    int
+	LeasedUntil time.Time
+	LastError   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
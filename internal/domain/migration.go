@@ -0,0 +1,34 @@
+package domain
+
+import "context"
+
+// Migration is one versioned step of the database schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes how far the database schema is from the embedded migrations
+type MigrationStatus struct {
+	// Current is the applied version, 0 when nothing was applied yet
+	Current int64
+	// Dirty is set when a migration failed halfway and the schema needs manual repair
+	Dirty   bool
+	Latest  int64
+	Applied []Migration
+	Pending []Migration
+}
+
+// UpToDate reports whether the schema matches the latest migration
+func (s MigrationStatus) UpToDate() bool {
+	return !s.Dirty && s.Current == s.Latest
+}
+
+// Migrator applies and rolls back schema migrations of a storage backend
+type Migrator interface {
+	MigrateUp(ctx context.Context, migrations []Migration) ([]Migration, error)
+	MigrateDown(ctx context.Context, migrations []Migration, steps int) ([]Migration, error)
+	MigrationStatus(ctx context.Context, migrations []Migration) (MigrationStatus, error)
+}
@@ -1,30 +1,66 @@
 package domain
 
 import (
+	"context"
 	"time"
 )
 
 // Repository defines all DB operations the app needs.
+// Every call takes a context so that it stops on Ctrl+C or when its deadline passes.
 type Repository interface {
 	// Feeds
-	AddFeed(feed Feed) error
-	ListFeeds(limit int) ([]Feed, error)
-	ListFeedByName(feedName string) (Feed, error)
-	DeleteFeed(name string) error
-	UpdateFeedTimestamp(feedID string, updatedAt time.Time) error
+	AddFeed(ctx context.Context, feed Feed) error
+	ListFeeds(ctx context.Context, filter FeedFilter, limit int) ([]Feed, error)
+	ListFeedByName(ctx context.Context, feedName string) (Feed, error)
+	ListDueFeeds(ctx context.Context, fetchedBefore time.Time) ([]Feed, error)
+	UpdateFeed(ctx context.Context, name string, feed Feed) error
+	DeleteFeed(ctx context.Context, name string) error
+	UpdateFeedTimestamp(ctx context.Context, feedID string, updatedAt time.Time) error
+	UpdateFeedMeta(ctx context.Context, feedID string, meta FeedMeta) error
+	SetFeedRetention(ctx context.Context, feedName string, policy RetentionPolicy) error
+	ListFeedRetention(ctx context.Context) (map[string]RetentionPolicy, error)
+	TagFeed(ctx context.Context, feedName string, tags []string) error
+	UntagFeed(ctx context.Context, feedName string, tags []string) error
 
 	// Articles
-	AddArticle(article Article) error
-	ListArticlesByFeed(feedID string, limit int) ([]Article, error)
-	ListArticles(feedName string, num int) ([]Article, error)
+	AddArticle(ctx context.Context, article Article) (ArticleStatus, error)
+	IngestFeed(ctx context.Context, feedID string, articles []Article, fetchedAt time.Time) (IngestStats, error)
+	ListArticlesByFeed(ctx context.Context, feedID string, filter ArticleFilter, limit int) ([]Article, error)
+	ListArticles(ctx context.Context, feedName string, num int) ([]Article, error)
+	SearchArticles(ctx context.Context, query SearchQuery) ([]SearchResult, error)
+	Timeline(ctx context.Context, query TimelineQuery) ([]TimelineEntry, error)
+	SetArticleRead(ctx context.Context, id string, read bool) error
+	SetArticleStarred(ctx context.Context, id string, starred bool) error
+	MarkFeedRead(ctx context.Context, feedID string) (int, error)
+	CountUnreadArticles(ctx context.Context) (map[string]int, error)
+	PruneArticles(ctx context.Context, feedID string, policy RetentionPolicy, now time.Time, dryRun bool) ([]Article, error)
+	ListPrunedArticles(ctx context.Context, feedID string) ([]PrunedArticle, error)
+	AddPrunedArticles(ctx context.Context, feedID string, pruned []PrunedArticle) error
+
+	// Fetch jobs
+	EnqueueFetchJob(ctx context.Context, feedID string, priority int) error
+	ClaimFetchJob(ctx context.Context, lease time.Duration) (FetchJob, error)
+	CompleteFetchJob(ctx context.Context, id int64) error
+	FailFetchJob(ctx context.Context, id int64, reason string) error
+	ReleaseFetchJob(ctx context.Context, id int64) error
+	CountQueuedFetchJobs(ctx context.Context) (int, error)
+	PurgeFetchJobs(ctx context.Context, finishedBefore time.Time) error
 
 	// Share
-	FetchCliInterval() (string, error)
-	SetInterval(interval string) error
-	SetDefaultCliIntervalAndWorkersNum(interval string, workersNum int) error
-	SetWorkers(workersNum int) error
-	FetchWorkersNumber() (int, error)
+	FetchCliInterval(ctx context.Context) (string, error)
+	SetInterval(ctx context.Context, interval string) error
+	SetDefaultCliIntervalAndWorkersNum(ctx context.Context, interval string, workersNum int) error
+	SetWorkers(ctx context.Context, workersNum int) error
+	FetchWorkersNumber(ctx context.Context) (int, error)
+	SetPauseState(ctx context.Context, state PauseState) error
+	FetchPauseState(ctx context.Context) (PauseState, error)
 
 	// Shutdown
 	Close() error
 }
+
+// Storage is a storage backend together with the migrations of its schema
+type Storage interface {
+	Repository
+	Migrator
+}
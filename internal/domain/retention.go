@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy limits which articles of a feed are kept. Zero fields mean no limit.
+// Starred articles are never pruned, whatever the policy says
+type RetentionPolicy struct {
+	// MaxArticles keeps only the N most recently published articles
+	MaxArticles int
+	// MaxAge drops articles published longer ago than this
+	MaxAge time.Duration
+}
+
+// IsZero reports whether the policy keeps everything
+func (p RetentionPolicy) IsZero() bool {
+	return p.MaxArticles <= 0 && p.MaxAge <= 0
+}
+
+// Merge returns p with every limit set in override taking precedence, so that
+// a feed's own rules replace the global ones field by field
+func (p RetentionPolicy) Merge(override RetentionPolicy) RetentionPolicy {
+	if override.MaxArticles > 0 {
+		p.MaxArticles = override.MaxArticles
+	}
+	if override.MaxAge > 0 {
+		p.MaxAge = override.MaxAge
+	}
+	return p
+}
+
+func (p RetentionPolicy) String() string {
+	var rules []string
+	if p.MaxArticles > 0 {
+		rules = append(rules, fmt.Sprintf("keep %d newest", p.MaxArticles))
+	}
+	if p.MaxAge > 0 {
+		if p.MaxAge%(24*time.Hour) == 0 {
+			rules = append(rules, fmt.Sprintf("keep %dd", p.MaxAge/(24*time.Hour)))
+		} else {
+			rules = append(rules, fmt.Sprintf("keep %v", p.MaxAge))
+		}
+	}
+	if len(rules) == 0 {
+		return "keep everything"
+	}
+	return strings.Join(rules, ", ")
+}
+
+// PrunedArticle is what is kept of an article removed by the retention rules,
+// enough for the next fetch not to store it again
+type PrunedArticle struct {
+	Link     string
+	GUID     string
+	PrunedAt time.Time
+}
+
+// PruneResult lists the articles of a feed that were pruned, or would be in a dry run
+type PruneResult struct {
+	Feed     Feed
+	Policy   RetentionPolicy
+	Articles []Article
+}
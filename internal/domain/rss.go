@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 var TimeLayouts = []string{
 	time.RFC1123Z,
@@ -17,16 +20,33 @@ var TimeLayouts = []string{
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Items       []RSSItem `xml:"item"`
+		Title string `xml:"title"`
+		// Links also collects <atom:link> elements, which have no text
+		Links       []string `xml:"link"`
+		Description string   `xml:"description"`
+		Language    string   `xml:"language"`
+		Image       struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
+		Items []RSSItem `xml:"item"`
 	} `xml:"channel"`
 }
 
+// SiteLink returns the link of the channel to its website
+func (f *RSSFeed) SiteLink() string {
+	for _, link := range f.Channel.Links {
+		if link = strings.TrimSpace(link); link != "" {
+			return link
+		}
+	}
+	return ""
+}
+
 type RSSItem struct {
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
 	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
 	PubDate     string `xml:"pubDate"`
 }
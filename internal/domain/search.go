@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// SearchQuery describes a full-text search over the stored articles
+type SearchQuery struct {
+	// Text is what the user typed, every word has to match
+	Text string
+	// FeedName limits the search to one feed when set
+	FeedName string
+	// Since skips articles published before it when set
+	Since time.Time
+	Limit int
+	// Offset skips that many of the best matches, for paging
+	Offset int
+}
+
+// SearchResult is an article matching a search, best matches have the highest Rank
+type SearchResult struct {
+	Article  Article
+	FeedName string
+	Rank     float64
+	// Snippet is a short excerpt with the matched words wrapped in HighlightStart and HighlightStop
+	Snippet string
+}
+
+// Markers around the matched words in SearchResult.Snippet
+const (
+	HighlightStart = "**"
+	HighlightStop  = "**"
+)
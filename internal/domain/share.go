@@ -7,9 +7,21 @@ import (
 
 type ShareVariables interface {
 	UpdateShare(dbInterval time.Duration, workersNum int, ctx context.Context)
+	Sync(ctx context.Context) error
 	Stop()
 }
 
+// PauseState tells whether background fetching is paused. A zero Until means paused until resumed
+type PauseState struct {
+	Paused bool
+	Until  time.Time
+}
+
+// Active reports whether the pause is still in effect at the given moment
+func (p PauseState) Active(now time.Time) bool {
+	return p.Paused && (p.Until.IsZero() || now.Before(p.Until))
+}
+
 // type StopWorker struct {
 // 	workerID int
 // }
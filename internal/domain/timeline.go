@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimelineQuery selects articles across feeds, newest first
+type TimelineQuery struct {
+	// FeedNames limits the timeline to these feeds, all feeds when empty
+	FeedNames []string
+	// Feeds further limits the timeline to the feeds of a folder or tag
+	Feeds FeedFilter
+	// Since and Until bound the publish time when set, Until is exclusive
+	Since  time.Time
+	Until  time.Time
+	Filter ArticleFilter
+	// After continues a previous page, only articles sorting after it are returned
+	After *TimelineCursor
+	Limit int
+}
+
+// TimelineEntry is an article of the timeline together with the name of its feed
+type TimelineEntry struct {
+	Article  Article
+	FeedName string
+}
+
+// TimelineCursor is the position of an article in the timeline, which is ordered
+// by publish time and then by id so that every position is unique
+type TimelineCursor struct {
+	PublishedAt time.Time
+	ID          string
+}
+
+var ErrInvalidCursor = errors.New("invalid timeline cursor")
+
+// CursorOf returns the position right after the given article
+func CursorOf(a Article) TimelineCursor {
+	return TimelineCursor{PublishedAt: a.PublishedAt, ID: a.ID}
+}
+
+// String encodes the cursor into an opaque token for --after
+func (c TimelineCursor) String() string {
+	raw := strconv.FormatInt(c.PublishedAt.UnixNano(), 10) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseTimelineCursor decodes a token made by TimelineCursor.String
+func ParseTimelineCursor(token string) (TimelineCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return TimelineCursor{}, ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return TimelineCursor{}, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return TimelineCursor{}, ErrInvalidCursor
+	}
+	return TimelineCursor{PublishedAt: time.Unix(0, n).UTC(), ID: id}, nil
+}
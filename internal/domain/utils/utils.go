@@ -1,10 +1,16 @@
 package utils
 
 import (
+	"RSSHub/internal/domain"
 	"RSSHub/pkg/config"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -62,6 +68,44 @@ func GetAndParseCliInterval() (time.Duration, error) {
 	return interval, nil
 }
 
+// DefaultShutdownTimeout is used when CLI_APP_SHUTDOWN_TIMEOUT is not set
+const DefaultShutdownTimeout = 30 * time.Second
+
+func GetAndParseShutdownTimeout() (time.Duration, error) {
+	envTimeout := config.GetEnvShutdownTimeout()
+	if envTimeout == "" {
+		return DefaultShutdownTimeout, nil
+	}
+
+	timeout, err := ParseIntervalToDuration(envTimeout)
+	if err != nil {
+		return 0, err
+	}
+	return timeout, nil
+}
+
+// GetRetentionPolicy reads the global retention rules. Unset variables mean no limit
+func GetRetentionPolicy() (domain.RetentionPolicy, error) {
+	var policy domain.RetentionPolicy
+
+	if maxArticles := config.GetEnvRetentionMaxArticles(); maxArticles != "" {
+		n, err := strconv.Atoi(maxArticles)
+		if err != nil || n < 0 {
+			return domain.RetentionPolicy{}, fmt.Errorf("invalid CLI_APP_RETENTION_MAX_ARTICLES %q", maxArticles)
+		}
+		policy.MaxArticles = n
+	}
+
+	if maxAge := config.GetEnvRetentionMaxAge(); maxAge != "" {
+		age, err := ParseAge(maxAge)
+		if err != nil {
+			return domain.RetentionPolicy{}, fmt.Errorf("invalid CLI_APP_RETENTION_MAX_AGE: %w", err)
+		}
+		policy.MaxAge = age
+	}
+	return policy, nil
+}
+
 func ParseDurationToInterval(duration time.Duration) (string, error) {
 	if duration <= 0 {
 		return "", errors.New("duration must be greater than zero")
@@ -87,6 +131,92 @@ func ParseDurationToInterval(duration time.Duration) (string, error) {
 	}
 }
 
+// ParseSince turns a --since/--until value into a moment: a date (2006-01-02), an RFC 3339 time,
+// or an age such as 90m, 12h, 2d or 3w counted back from now
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	age, err := ParseAge(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, use a date like 2006-01-02 or an age like 2d", value)
+	}
+	return now.Add(-age), nil
+}
+
+// ParseAge parses an age such as 90m, 12h, 30d or 3w. Unlike intervals it has no upper bound
+func ParseAge(value string) (time.Duration, error) {
+	if len(value) < 2 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+
+	switch value[len(value)-1] {
+	case 'm':
+		return time.Duration(n) * time.Minute, nil
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unsupported unit in %q, use m, h, d or w", value)
+	}
+}
+
+// NewUUID returns a random UUID, the same kind of id Postgres generates with gen_random_uuid()
+func NewUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// IsUUID reports whether s looks like an id made by NewUUID or Postgres
+func IsUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// ParseTags splits a comma-separated list of tags. Tags are lowercased and deduplicated
+// and may contain letters, digits, '-', '_' and '.'
+func ParseTags(value string) ([]string, error) {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q, use letters, digits, '-', '_' and '.'", tag)
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// StripHTML drops the tags from an HTML fragment and collapses the whitespace left behind
+func StripHTML(s string) string {
+	return strings.Join(strings.Fields(htmlTag.ReplaceAllString(s, " ")), " ")
+}
+
 func GetAndParseWorkersNum() (int, error) {
 	workersNum := config.GetEnvWorkersNum()
 	num, err := strconv.Atoi(workersNum)
@@ -101,17 +231,41 @@ func PrintHelp() {
   rsshub COMMAND [OPTIONS]
 
 Common Commands:
-   add             add new RSS feed
+   migrate         apply (up), roll back (down [--steps N]) or show (status) database migrations
+   add             add new RSS feed (--folder, --tag a,b to organize it); for a web page --url, its feeds are looked up (--pick N)
+   tag             add tags to a feed (--name, --tag a,b)
+   untag           remove tags from a feed (--name, --tag a,b)
+   import          add the feeds of an OPML file from another reader (--opml file, --validate to fetch them first)
+   export          write all feeds as OPML 2.0 for other readers (--opml, -o file)
+   backup          save feeds, articles, read/star state and settings to an archive (-o file.tar.gz)
+   restore         load a backup archive (-i file.tar.gz, --on-conflict skip|merge|replace)
+   update          rename a feed or change its URL, fetch interval or folder (--name, --new-name, --url, --interval, --folder)
    set-interval    set RSS fetch interval
    set-workers     set number of workers
-   list            list available RSS feeds
+   pause           pause background fetching, optionally --for a duration
+   resume          resume background fetching
+   status          show the state of the background fetcher
+   reload          make the running fetch process re-read its settings from db
+   list            list available RSS feeds (--folder, --tag to filter, --verbose for titles, sites and icons)
+   info            show everything known about a feed (--name)
    delete          delete RSS feed
-   articles        show latest articles
+   articles        show latest articles of a feed, folder or tag (--unread, --starred to filter)
+   timeline        articles of all feeds, newest first ([--feed a,b] [--since 2d] [--until 1d] [--folder f] [--tag t] [--after cursor] [--unread])
+   read            mark an article as read (--id, --undo to mark it unread)
+   star            star an article so it is never pruned (--id, --undo to remove the star)
+   mark-all-read   mark every article of a feed as read (--feed)
+   prune           delete old articles according to the retention rules (--dry-run to only list them)
+   set-retention   set the retention rules of a feed (--name, --keep N, --max-age 30d)
+   search          full-text search in stored articles ("query" [--feed name] [--since 7d] [--limit N])
+   fetch-now       fetch a feed (--name), a folder (--folder), a tag (--tag) or all feeds (--all) right away
    fetch           starts the background process that periodically fetches and processes RSS feeds using a worker pool
+                   (--once fetches all due feeds a single time and exits, for cron jobs)
 
 Examples:
   rsshub --help
-  rsshub add --name TechCrunch --url https://techcrunch.com/feed/
+  rsshub add --name TechCrunch --url https://techcrunch.com/feed/ --folder news --tag tech,daily
   rsshub list
-  rsshub fetch`)
+  rsshub search "climate policy" --since 7d
+  rsshub fetch
+  rsshub status`)
 }
@@ -1 +1 @@
-DROP TABLE IF EXISTS feeds;
\ No newline at end of file
+DROP TABLE IF EXISTS feeds;
@@ -1 +1 @@
-DROP TABLE IF EXISTS articles;
\ No newline at end of file
+DROP TABLE IF EXISTS articles;
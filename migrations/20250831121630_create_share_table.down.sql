@@ -1 +1 @@
-DROP TABLE IF EXISTS share;
\ No newline at end of file
+DROP TABLE IF EXISTS share;
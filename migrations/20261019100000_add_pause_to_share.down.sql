@@ -0,0 +1,3 @@
+ALTER TABLE share
+    DROP COLUMN IF EXISTS paused_until,
+    DROP COLUMN IF EXISTS paused;
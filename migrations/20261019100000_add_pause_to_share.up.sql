@@ -0,0 +1,3 @@
+ALTER TABLE share
+    ADD COLUMN paused BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN paused_until TIMESTAMP;
@@ -0,0 +1,10 @@
+ALTER TABLE articles ADD COLUMN content TEXT NOT NULL DEFAULT '';
+
+-- Title matches weigh more than description matches, which weigh more than body matches
+ALTER TABLE articles ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', content), 'C')
+) STORED;
+
+CREATE INDEX articles_search_vector_idx ON articles USING GIN (search_vector);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS articles_feed_published_idx;
+DROP TABLE IF EXISTS pruned_articles;
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS retention_max_age_seconds,
+    DROP COLUMN IF EXISTS retention_max_articles;
+ALTER TABLE articles DROP COLUMN IF EXISTS starred;
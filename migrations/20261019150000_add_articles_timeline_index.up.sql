@@ -0,0 +1 @@
+CREATE INDEX articles_timeline_idx ON articles (published_at DESC, id DESC);
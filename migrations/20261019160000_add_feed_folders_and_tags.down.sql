@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS feed_tags;
+DROP INDEX IF EXISTS feeds_folder_idx;
+ALTER TABLE feeds DROP COLUMN IF EXISTS folder;
@@ -0,0 +1,8 @@
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS icon_checked_at,
+    DROP COLUMN IF EXISTS icon_url,
+    DROP COLUMN IF EXISTS image_url,
+    DROP COLUMN IF EXISTS language,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS site_url,
+    DROP COLUMN IF EXISTS title;
@@ -0,0 +1,9 @@
+-- What the feed says about itself, refreshed on every fetch
+ALTER TABLE feeds
+    ADD COLUMN title TEXT NOT NULL DEFAULT '',
+    ADD COLUMN site_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN language TEXT NOT NULL DEFAULT '',
+    ADD COLUMN image_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN icon_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN icon_checked_at TIMESTAMP;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS pruned_articles_guid_idx;
+ALTER TABLE pruned_articles DROP COLUMN IF EXISTS guid;
+ALTER TABLE articles DROP COLUMN IF EXISTS guid;
@@ -0,0 +1,10 @@
+CREATE TABLE articles (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    link TEXT NOT NULL UNIQUE,
+    description TEXT,
+    published_at TIMESTAMP,
+    feed_id TEXT REFERENCES feeds(id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+CREATE TABLE share (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    interval TEXT NOT NULL,
+    workers_num INTEGER NOT NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE share DROP COLUMN paused_until;
+ALTER TABLE share DROP COLUMN paused;
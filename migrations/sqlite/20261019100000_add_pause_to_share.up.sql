@@ -0,0 +1,2 @@
+ALTER TABLE share ADD COLUMN paused BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE share ADD COLUMN paused_until TIMESTAMP;
@@ -0,0 +1,16 @@
+CREATE TABLE fetch_jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    state TEXT NOT NULL DEFAULT 'queued' CHECK (state IN ('queued', 'running', 'done', 'failed')),
+    priority INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    leased_until TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- A feed can have only one job waiting or in progress at a time
+CREATE UNIQUE INDEX fetch_jobs_active_feed_idx ON fetch_jobs (feed_id) WHERE state IN ('queued', 'running');
+
+CREATE INDEX fetch_jobs_pick_idx ON fetch_jobs (priority DESC, created_at) WHERE state IN ('queued', 'running');
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS articles_fts_after_insert;
+DROP TRIGGER IF EXISTS articles_fts_after_update;
+DROP TRIGGER IF EXISTS articles_fts_before_delete;
+DROP TRIGGER IF EXISTS articles_fts_before_update;
+DROP TABLE IF EXISTS articles_fts;
+ALTER TABLE articles DROP COLUMN content;
@@ -0,0 +1,24 @@
+ALTER TABLE articles ADD COLUMN content TEXT NOT NULL DEFAULT '';
+
+-- FTS4 index over the articles table, kept in sync by the triggers below
+CREATE VIRTUAL TABLE articles_fts USING fts4(content="articles", title, description, content, tokenize=porter);
+
+INSERT INTO articles_fts (docid, title, description, content)
+SELECT rowid, title, description, content FROM articles;
+
+-- Only changes of the indexed columns touch the index, not read or starred flags
+CREATE TRIGGER articles_fts_before_update BEFORE UPDATE OF title, description, content ON articles BEGIN
+    DELETE FROM articles_fts WHERE docid = old.rowid;
+END;
+
+CREATE TRIGGER articles_fts_before_delete BEFORE DELETE ON articles BEGIN
+    DELETE FROM articles_fts WHERE docid = old.rowid;
+END;
+
+CREATE TRIGGER articles_fts_after_update AFTER UPDATE OF title, description, content ON articles BEGIN
+    INSERT INTO articles_fts (docid, title, description, content) VALUES (new.rowid, new.title, new.description, new.content);
+END;
+
+CREATE TRIGGER articles_fts_after_insert AFTER INSERT ON articles BEGIN
+    INSERT INTO articles_fts (docid, title, description, content) VALUES (new.rowid, new.title, new.description, new.content);
+END;
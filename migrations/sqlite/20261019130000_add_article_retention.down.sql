@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS articles_feed_published_idx;
+DROP TABLE IF EXISTS pruned_articles;
+ALTER TABLE feeds DROP COLUMN retention_max_age_seconds;
+ALTER TABLE feeds DROP COLUMN retention_max_articles;
+ALTER TABLE articles DROP COLUMN starred;
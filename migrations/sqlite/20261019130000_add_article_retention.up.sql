@@ -0,0 +1,14 @@
+ALTER TABLE articles ADD COLUMN starred BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Per-feed retention rules, 0 means the global rule applies
+ALTER TABLE feeds ADD COLUMN retention_max_articles INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN retention_max_age_seconds INTEGER NOT NULL DEFAULT 0;
+
+-- Links of pruned articles, so that the next fetch does not store them again
+CREATE TABLE pruned_articles (
+    link TEXT PRIMARY KEY,
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    pruned_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX articles_feed_published_idx ON articles (feed_id, published_at DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE articles ADD COLUMN read BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX articles_unread_idx ON articles (feed_id) WHERE NOT read;
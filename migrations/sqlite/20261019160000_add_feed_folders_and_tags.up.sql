@@ -0,0 +1,12 @@
+-- A feed belongs to at most one folder, '' means none
+ALTER TABLE feeds ADD COLUMN folder TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX feeds_folder_idx ON feeds (folder);
+
+CREATE TABLE feed_tags (
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (feed_id, tag)
+);
+
+CREATE INDEX feed_tags_tag_idx ON feed_tags (tag);
@@ -0,0 +1,2 @@
+-- Per-feed fetch interval, 0 means the global interval applies
+ALTER TABLE feeds ADD COLUMN fetch_interval_seconds INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+ALTER TABLE feeds DROP COLUMN icon_checked_at;
+ALTER TABLE feeds DROP COLUMN icon_url;
+ALTER TABLE feeds DROP COLUMN image_url;
+ALTER TABLE feeds DROP COLUMN language;
+ALTER TABLE feeds DROP COLUMN description;
+ALTER TABLE feeds DROP COLUMN site_url;
+ALTER TABLE feeds DROP COLUMN title;
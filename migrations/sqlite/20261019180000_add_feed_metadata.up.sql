@@ -0,0 +1,8 @@
+-- What the feed says about itself, refreshed on every fetch
+ALTER TABLE feeds ADD COLUMN title TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN site_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN language TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN image_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN icon_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN icon_checked_at TIMESTAMP;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS pruned_articles_guid_idx;
+ALTER TABLE pruned_articles DROP COLUMN guid;
+ALTER TABLE articles DROP COLUMN guid;
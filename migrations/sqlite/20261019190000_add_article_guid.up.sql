@@ -0,0 +1,6 @@
+-- The <guid> of the item, empty when the feed gives none
+ALTER TABLE articles ADD COLUMN guid TEXT NOT NULL DEFAULT '';
+
+-- Pruned items are also recognised by their GUID, so that a rewritten link does not bring them back
+ALTER TABLE pruned_articles ADD COLUMN guid TEXT NOT NULL DEFAULT '';
+CREATE INDEX pruned_articles_guid_idx ON pruned_articles (guid) WHERE guid <> '';
@@ -22,3 +22,33 @@ func GetEnvWorkersNum() string {
 	logger.Debug("Getting env value of workers", "workers", workers)
 	return workers
 }
+
+func GetEnvShutdownTimeout() string {
+	timeout := os.Getenv("CLI_APP_SHUTDOWN_TIMEOUT")
+	logger.Debug("Getting env value of shutdown timeout", "shutdown_timeout", timeout)
+	return timeout
+}
+
+func GetEnvStorage() string {
+	storage := os.Getenv("RSSHUB_STORAGE")
+	logger.Debug("Getting env value of storage", "storage", storage)
+	return storage
+}
+
+func GetEnvControlSocket() string {
+	socket := os.Getenv("RSSHUB_CONTROL_SOCKET")
+	logger.Debug("Getting env value of control socket", "control_socket", socket)
+	return socket
+}
+
+func GetEnvRetentionMaxArticles() string {
+	maxArticles := os.Getenv("CLI_APP_RETENTION_MAX_ARTICLES")
+	logger.Debug("Getting env value of retention max articles", "retention_max_articles", maxArticles)
+	return maxArticles
+}
+
+func GetEnvRetentionMaxAge() string {
+	maxAge := os.Getenv("CLI_APP_RETENTION_MAX_AGE")
+	logger.Debug("Getting env value of retention max age", "retention_max_age", maxAge)
+	return maxAge
+}
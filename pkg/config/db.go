@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"RSSHub/pkg/logger"
+)
+
+// sslModes are the TLS modes understood by the Postgres driver
+var sslModes = map[string]bool{
+	"disable":     true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// DBConfig holds everything needed to open and keep the Postgres connection pool
+type DBConfig struct {
+	// DSN is taken from DATABASE_URL as is, or built from the POSTGRES_* variables
+	DSN string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// ConnectRetries is how many times the first connection is retried, waiting
+	// RetryBackoff before the first retry and twice as long before every next one
+	ConnectRetries int
+	RetryBackoff   time.Duration
+}
+
+// GetDBConfig reads the database settings from the environment
+func GetDBConfig() (DBConfig, error) {
+	cfg := DBConfig{}
+	var err error
+
+	if cfg.MaxOpenConns, err = envInt("POSTGRES_MAX_OPEN_CONNS", 10); err != nil {
+		return DBConfig{}, err
+	}
+	if cfg.MaxIdleConns, err = envInt("POSTGRES_MAX_IDLE_CONNS", 5); err != nil {
+		return DBConfig{}, err
+	}
+	if cfg.ConnMaxLifetime, err = envDuration("POSTGRES_CONN_MAX_LIFETIME", 30*time.Minute); err != nil {
+		return DBConfig{}, err
+	}
+	if cfg.ConnectRetries, err = envInt("POSTGRES_CONNECT_RETRIES", 5); err != nil {
+		return DBConfig{}, err
+	}
+	if cfg.RetryBackoff, err = envDuration("POSTGRES_RETRY_BACKOFF", 500*time.Millisecond); err != nil {
+		return DBConfig{}, err
+	}
+
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		logger.Debug("Using DATABASE_URL for the db connection")
+		cfg.DSN = dsn
+		return cfg, nil
+	}
+
+	sslMode := envString("POSTGRES_SSLMODE", "disable")
+	if !sslModes[sslMode] {
+		return DBConfig{}, fmt.Errorf("unsupported POSTGRES_SSLMODE %q", sslMode)
+	}
+
+	query := url.Values{}
+	query.Set("sslmode", sslMode)
+	if rootCert := os.Getenv("POSTGRES_SSLROOTCERT"); rootCert != "" {
+		query.Set("sslrootcert", rootCert)
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(envString("POSTGRES_USER", "postgres"), os.Getenv("POSTGRES_PASSWORD")),
+		Host:     net.JoinHostPort(envString("POSTGRES_HOST", "localhost"), envString("POSTGRES_PORT", "5432")),
+		Path:     envString("POSTGRES_DBNAME", "rsshub"),
+		RawQuery: query.Encode(),
+	}
+	cfg.DSN = dsn.String()
+	logger.Debug("Using POSTGRES_* variables for the db connection", "host", dsn.Host, "dbname", dsn.Path, "sslmode", sslMode)
+	return cfg, nil
+}
+
+func envString(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	num, err := strconv.Atoi(value)
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("invalid %s value %q", key, value)
+	}
+	return num, nil
+}
+
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s value %q", key, value)
+	}
+	return d, nil
+}
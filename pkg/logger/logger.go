@@ -6,10 +6,12 @@ import (
 	"os"
 )
 
-var Log *slog.Logger
+// Log falls back to the default logger until Init is called, e.g. in tests
+var Log = slog.Default()
 
 func Init() {
-	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	// Logs go to stderr, so that the output of commands such as export can be redirected
+	handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
 		Level: slog.LevelDebug, // Uncomment this for debug purposes
 	})
 	Log = slog.New(handler)